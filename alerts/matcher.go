@@ -0,0 +1,119 @@
+// Package alerts notifies users of new ads that match their saved
+// searches.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+// Interval is how often Run looks for unmatched ads when nothing wakes it
+// up, which retries the matches that failed.
+const Interval = time.Minute
+
+// batchSize is the number of unmatched ads loaded at once.
+const batchSize = 100
+
+// Matcher evaluates new ads against the saved searches in the background.
+// For every match it stores a notification and publishes it to the user.
+// The ads waiting to be matched are kept by the ad repository, so none is
+// lost when the matcher falls behind or the server stops.
+type Matcher struct {
+	repos  repository.Repositories
+	broker events.Broker
+	wake   chan struct{}
+}
+
+func NewMatcher(repos repository.Repositories, broker events.Broker) *Matcher {
+	return &Matcher{repos: repos, broker: broker, wake: make(chan struct{}, 1)}
+}
+
+// Wake tells Run that an ad went active so that it is matched without
+// waiting for the next interval. It does not block.
+func (m *Matcher) Wake() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run matches the unmatched ads every interval and whenever it is woken
+// up, until ctx is canceled.
+func (m *Matcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.MatchPending(ctx); err != nil {
+			log.Printf("Error matching ads against saved searches: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// MatchPending matches the unmatched ads until none is left. An ad whose
+// match fails stays unmatched and is retried by the next call.
+func (m *Matcher) MatchPending(ctx context.Context) error {
+	for {
+		ids, err := m.repos.Ads.Unmatched(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+
+		failed := false
+		for _, id := range ids {
+			if err := m.Match(ctx, id); err != nil {
+				log.Printf("Error matching ad %d against saved searches: %v", id, err)
+				failed = true
+				continue
+			}
+			if err := m.repos.Ads.MarkMatched(ctx, id); err != nil {
+				return err
+			}
+		}
+
+		if failed || len(ids) < batchSize {
+			return nil
+		}
+	}
+}
+
+// Match notifies the owners of the saved searches the ad matches. Users
+// already notified of the ad for a search are not notified again.
+func (m *Matcher) Match(ctx context.Context, adID uint) error {
+	searches, err := m.repos.SavedSearches.Matching(ctx, adID)
+	if err != nil {
+		return err
+	}
+
+	for _, search := range searches {
+		notification := models.Notification{
+			UserID:        search.UserID,
+			AdID:          adID,
+			SavedSearchID: search.ID,
+		}
+		err := m.repos.Notifications.Create(ctx, &notification)
+		if errors.Is(err, repository.ErrDuplicate) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		m.broker.Publish(events.Event{Type: events.AdMatched, Data: notification}, search.UserID)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/repository/memory"
+)
+
+// flakyNotifications fails to store notifications while down is set.
+type flakyNotifications struct {
+	repository.NotificationRepository
+	down bool
+}
+
+func (n *flakyNotifications) Create(ctx context.Context, notification *models.Notification) error {
+	if n.down {
+		return errors.New("database is down")
+	}
+	return n.NotificationRepository.Create(ctx, notification)
+}
+
+func TestMatchPending(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.New()
+	notifications := &flakyNotifications{NotificationRepository: repos.Notifications}
+	repos.Notifications = notifications
+	matcher := NewMatcher(repos, events.NewMemory())
+
+	seller := models.User{Name: "seller", Email: "seller@example.com", PhoneNumber: "+1"}
+	buyer := models.User{Name: "buyer", Email: "buyer@example.com", PhoneNumber: "+2"}
+	for _, user := range []*models.User{&seller, &buyer} {
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	category := models.Category{Name: "Garden"}
+	if err := repos.Categories.Create(ctx, &category); err != nil {
+		t.Fatal(err)
+	}
+	subcategory := models.Subcategory{Name: "Tools", CategoryID: category.ID}
+	if err := repos.Subcategories.Create(ctx, &subcategory); err != nil {
+		t.Fatal(err)
+	}
+	search := models.SavedSearch{UserID: buyer.ID, Name: "Tools", SubcategoryID: &subcategory.ID}
+	if err := repos.SavedSearches.Create(ctx, &search); err != nil {
+		t.Fatal(err)
+	}
+
+	ad := func(status string) uint {
+		ad := models.Advertisement{
+			Title:          status,
+			Subcategory_id: subcategory.ID,
+			User_id:        seller.ID,
+			Status:         status,
+		}
+		if err := repos.Ads.Create(ctx, &ad); err != nil {
+			t.Fatal(err)
+		}
+		return ad.ID
+	}
+	notified := func() []uint {
+		t.Helper()
+		list, err := repos.Notifications.List(ctx, buyer.ID, 100, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []uint
+		for _, notification := range list {
+			ids = append(ids, notification.AdID)
+		}
+		slices.Sort(ids)
+		return ids
+	}
+	run := func() {
+		t.Helper()
+		if err := matcher.MatchPending(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := ad(models.StatusActive)
+	draft := ad(models.StatusDraft)
+	run()
+	if got, want := notified(), []uint{first}; !slices.Equal(got, want) {
+		t.Fatalf("notified of %v, want %v", got, want)
+	}
+	if pending, _ := repos.Ads.Unmatched(ctx, batchSize); len(pending) != 0 {
+		t.Errorf("ads %v are still unmatched", pending)
+	}
+
+	notifications.down = true
+	second := ad(models.StatusActive)
+	run()
+	if pending, _ := repos.Ads.Unmatched(ctx, batchSize); !slices.Equal(pending, []uint{second}) {
+		t.Errorf("unmatched ads %v, want %v", pending, []uint{second})
+	}
+
+	notifications.down = false
+	if err := repos.Ads.SetStatus(ctx, draft, models.StatusDraft, models.StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	run()
+	if got, want := notified(), []uint{first, draft, second}; !slices.Equal(got, want) {
+		t.Errorf("notified of %v, want %v", got, want)
+	}
+}
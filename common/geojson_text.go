@@ -17,11 +17,15 @@ func (gt *GeoJSONText) Scan(value interface{}) error {
 	}
 
 	gt.Data = json.RawMessage(byteValue)
-	log.Printf("gt.Data =", string(gt.Data))
+	log.Printf("gt.Data = %s", string(gt.Data))
 	return nil
 }
 
+// MarshalJSON renders an unset location as null.
 func (gt GeoJSONText) MarshalJSON() ([]byte, error) {
+	if len(gt.Data) == 0 {
+		return []byte("null"), nil
+	}
 	return gt.Data, nil
 }
 
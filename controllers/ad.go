@@ -6,241 +6,271 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/geojson"
-	"github.com/sciphilib/go-dacha/common"
+	"github.com/sciphilib/go-dacha/events"
 	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
 	"github.com/sciphilib/go-dacha/utils"
-	"gorm.io/gorm"
 )
 
-type ReadAd struct {
-	models.Advertisement
-	SubcategoryID   uint           `json:"subcategory_id"`
-	SubcategoryName string         `json:"subcategory_name"`
-	CategoryName    string         `json:"category_name"`
-	LocationText    string         `json:"location"`
-	Pictures        pq.StringArray `gorm:"column:pictures" json:"pictures"`
+// formatAd renders an ad with its subcategory and owner in the shape of
+// models.AdResponse, naming the subcategory and category in the locale of
+// l. The owner's contact details are left out; buyers reach the seller
+// through a conversation instead.
+func (s *Server) formatAd(ad models.AdDetails, l localizer) map[string]interface{} {
+	formatted := map[string]interface{}{
+		"id":          ad.ID,
+		"title":       ad.Title,
+		"price":       ad.Price,
+		"description": ad.Description,
+		"subcategory": map[string]interface{}{
+			"id":       ad.Subcategory.ID,
+			"name":     l.subcategory(ad.Subcategory.ID, ad.Subcategory.Name),
+			"category": l.category(ad.Subcategory.CategoryID, ad.Subcategory.Category),
+		},
+		"user": map[string]interface{}{
+			"id":       ad.User.ID,
+			"name":     ad.User.Name,
+			"location": ad.User.LocationText,
+		},
+		"datetime":   ad.Datetime,
+		"pictures":   s.pictures(ad.PicturesText),
+		"location":   ad.LocationText,
+		"status":     ad.Status,
+		"expires_at": ad.ExpiresAt,
+		"attributes": ad.Attributes,
+	}
+	if ad.Distance != nil {
+		formatted["distance"] = *ad.Distance
+	}
+	return formatted
+}
+
+// formatAds renders ads with formatAd in the locale the request accepts.
+// When the request is authenticated each ad also carries is_favorite.
+func (s *Server) formatAds(r *http.Request, ads []models.AdDetails) ([]map[string]interface{}, error) {
+	l, err := s.localize(r)
+	if err != nil {
+		return nil, err
+	}
+
+	formatted := make([]map[string]interface{}, len(ads))
+	for i, ad := range ads {
+		formatted[i] = s.formatAd(ad, l)
+	}
+
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		return formatted, nil
+	}
+
+	ids := make([]uint, len(ads))
+	for i, ad := range ads {
+		ids[i] = ad.ID
+	}
+	favorites, err := s.repos.Favorites.Contains(r.Context(), userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, ad := range ads {
+		formatted[i]["is_favorite"] = favorites[ad.ID]
+	}
+
+	return formatted, nil
 }
 
 // GetAllAds godoc
 // @Summary Get all ads
-// @Description Retrieves a list of all advertisements with detailed information
+// @Description Retrieves a page of advertisements with detailed information. Filters are combined with AND. Ads are also filtered by attribute with attr.<name>=<value>, and numeric attributes by range with attr.<name>.min and attr.<name>.max.
 // @Tags advertisements
 // @Accept json
 // @Produce json
-// @Success 200 {array} models.AdResponse "An array of advertisement objects"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param cursor query int false "Return ads with ID greater than this; use next_cursor from the previous page"
+// @Param offset query int false "Number of ads to skip; cannot be combined with cursor"
+// @Param category_id query int false "Category ID; matches ads in the category and all categories below it"
+// @Param subcategory_id query int false "Subcategory ID"
+// @Param price_min query int false "Minimum price in minor units; excludes free ads"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param currency query string false "ISO 4217 currency code"
+// @Param from query string false "Earliest ad datetime (RFC 3339)"
+// @Param to query string false "Latest ad datetime (RFC 3339)"
+// @Param user_id query int false "Owner user ID"
+// @Param q query string false "Full-text search over title and description; results are ranked by relevance and paged with offset"
+// @Param sort query string false "price_asc or price_desc; paged with offset" Enums(price_asc, price_desc)
+// @Param status query string false "Ad status (default active); drafts, pending, rejected and hidden ads are only listed for their owner's user_id or to moderators" Enums(draft, active, reserved, sold, expired, rejected, hidden, pending)
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {object} models.AdPage "A page of advertisement objects"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 403 {object} string "Drafts, pending, rejected and hidden ads are only visible to their owner"
 // @Failure 500 {object} nil "Internal Server Error"
 // @Router /ads [get]
-func GetAllAds(w http.ResponseWriter, r *http.Request) {
-	var result []ReadAd
-
-	err := models.DB.Raw(`
-	       SELECT
-	             advertisements.*,
-	             subcategories.id AS subcategory_id,
-	             subcategories.name AS subcategory_name,
-	             categories.name AS category_name,
-	             ST_AsGeoJSON(advertisements.location::geometry) AS location_text
-	       FROM advertisements
-	       JOIN subcategories ON subcategories.id = advertisements.subcategory_id
-	       JOIN categories ON categories.id = subcategories.category_id
-	    `).
-		Scan(&result).Error
+func (s *Server) GetAllAds(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAdFilter(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	if !canListStatus(r, filter) {
+		utils.RespondWithError(w, http.StatusForbidden, "Drafts, pending, rejected and hidden ads are only visible to their owner")
+		return
+	}
 
+	page, err := s.repos.Ads.List(r.Context(), filter)
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	type user_result struct {
-		models.User
-		LocationText string `json:"location"`
+	pagination := models.Pagination{
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		Total:   page.Total,
+		HasMore: page.HasMore,
+	}
+	if page.HasMore && filter.Query == "" && filter.Sort == "" {
+		pagination.NextCursor = page.Ads[len(page.Ads)-1].ID
 	}
-	var json_users []user_result
-
-	err = models.DB.Raw(`
-	    SELECT *, ST_AsGeoJSON(users.location::geometry) AS location_text
-	    FROM users`).
-		Scan(&json_users).Error
 
+	formatted, err := s.formatAds(r, page.Ads)
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	users := make([]models.User, len(json_users))
-	for i, r := range json_users {
-		if r.User.LocationEWKB == nil {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-		} else {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		}
-		users[i] = r.User
+	respondWithAdPage(w, formatted, pagination)
+}
+
+// SearchAds godoc
+// @Summary Search ads by location
+// @Description Retrieves advertisements within a radius around a point or inside a bounding box, nearest first. Each ad carries its distance in meters from the point, or from the box center when only a box is given. Accepts the same filters as GET /ads except cursor and sort, which are rejected.
+// @Tags advertisements
+// @Accept json
+// @Produce json
+// @Param lat query number false "Latitude of the search center"
+// @Param lon query number false "Longitude of the search center"
+// @Param radius query number false "Search radius in meters, required with lat and lon unless bbox is given"
+// @Param bbox query string false "Bounding box as minLon,minLat,maxLon,maxLat"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of ads to skip"
+// @Param category_id query int false "Category ID; matches ads in the category and all categories below it"
+// @Param subcategory_id query int false "Subcategory ID"
+// @Param price_min query int false "Minimum price in minor units; excludes free ads"
+// @Param price_max query int false "Maximum price in minor units"
+// @Param currency query string false "ISO 4217 currency code"
+// @Param from query string false "Earliest ad datetime (RFC 3339)"
+// @Param to query string false "Latest ad datetime (RFC 3339)"
+// @Param user_id query int false "Owner user ID"
+// @Param q query string false "Full-text search over title and description"
+// @Param status query string false "Ad status (default active); drafts, pending, rejected and hidden ads are only listed for their owner's user_id or to moderators" Enums(draft, active, reserved, sold, expired, rejected, hidden, pending)
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {object} models.AdPage "A page of advertisement objects with distance"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 403 {object} string "Drafts, pending, rejected and hidden ads are only visible to their owner"
+// @Failure 500 {object} nil "Internal Server Error"
+// @Router /ads/search [get]
+func (s *Server) SearchAds(w http.ResponseWriter, r *http.Request) {
+	// Search results are always ordered by distance and paged with
+	// offset, so sort and cursor are refused rather than ignored.
+	if r.URL.Query().Has("sort") || r.URL.Query().Has("cursor") {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
 	}
 
-	userMap := make(map[uint]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
+	filter, err := parseAdFilter(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
 	}
 
-	ads := make([]models.Advertisement, len(result))
-	for i, r := range result {
-		r.Advertisement.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		r.Advertisement.Subcategory.ID = r.SubcategoryID
-		r.Advertisement.Subcategory.Name = r.SubcategoryName
-		r.Advertisement.Subcategory.Category = r.CategoryName
-		ads[i] = r.Advertisement
-		ads[i].PicturesText = make([]string, len(r.Pictures))
-		copy(ads[i].PicturesText, r.Pictures)
+	if !canListStatus(r, filter) {
+		utils.RespondWithError(w, http.StatusForbidden, "Drafts, pending, rejected and hidden ads are only visible to their owner")
+		return
 	}
 
-	var formattedAds []map[string]interface{}
+	geo, err := parseGeoFilter(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
 
-	for _, ad := range ads {
-		user, exists := userMap[ad.User_id]
-		if !exists {
-			log.Printf("User with ID %d not found", ad.User_id)
-			continue
-		}
+	page, err := s.repos.Ads.Search(r.Context(), filter, geo)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
 
-		formattedAd := map[string]interface{}{
-			"id":          ad.ID,
-			"title":       ad.Title,
-			"price":       ad.Price,
-			"description": ad.Description,
-			"subcategory": map[string]interface{}{
-				"name":     ad.Subcategory.Name,
-				"category": ad.Subcategory.Category,
-			},
-			"user":     user,
-			"datetime": ad.Datetime,
-			"pictures": ad.PicturesText,
-			"location": ad.LocationText,
-		}
-		formattedAds = append(formattedAds, formattedAd)
+	pagination := models.Pagination{
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+		Total:   page.Total,
+		HasMore: page.HasMore,
 	}
 
+	formatted, err := s.formatAds(r, page.Ads)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithAdPage(w, formatted, pagination)
+}
+
+func respondWithAdPage(w http.ResponseWriter, ads []map[string]interface{}, pagination models.Pagination) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(formattedAds); err != nil {
+	err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":      ads,
+		"pagination": pagination,
+	})
+	if err != nil {
 		log.Printf("Serialization error: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
 	}
 }
 
 // GetNewestAds godoc
 // @Summary Get all ads ordered by date
-// @Description Retrieves a list of all advertisements from newest to oldest
+// @Description Retrieves a list of all active advertisements from newest to oldest
 // @Tags advertisements
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {array} models.AdResponse "An array of advertisement objects"
 // @Failure 500 {object} nil "Internal Server Error"
 // @Router /ads/newest [get]
-func GetNewestAds(w http.ResponseWriter, r *http.Request) {
-	var result []ReadAd
-
-	err := models.DB.Raw(`
-	       SELECT
-	             advertisements.*,
-	             subcategories.id AS subcategory_id,
-	             subcategories.name AS subcategory_name,
-	             categories.name AS category_name,
-	             ST_AsGeoJSON(advertisements.location::geometry) AS location_text
-	       FROM advertisements
-	       JOIN subcategories ON subcategories.id = advertisements.subcategory_id
-	       JOIN categories ON categories.id = subcategories.category_id
-           ORDER BY datetime DESC
-	    `).
-		Scan(&result).Error
-
+func (s *Server) GetNewestAds(w http.ResponseWriter, r *http.Request) {
+	ads, err := s.repos.Ads.Newest(r.Context())
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	type user_result struct {
-		models.User
-		LocationText string `json:"location"`
-	}
-	var json_users []user_result
-
-	err = models.DB.Raw(`
-	    SELECT *, ST_AsGeoJSON(users.location::geometry) AS location_text
-	    FROM users`).
-		Scan(&json_users).Error
-
+	formatted, err := s.formatAds(r, ads)
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	users := make([]models.User, len(json_users))
-	for i, r := range json_users {
-		if r.User.LocationEWKB == nil {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-		} else {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		}
-		users[i] = r.User
-	}
-
-	userMap := make(map[uint]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
-	}
-
-	ads := make([]models.Advertisement, len(result))
-	for i, r := range result {
-		r.Advertisement.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		r.Advertisement.Subcategory.ID = r.SubcategoryID
-		r.Advertisement.Subcategory.Name = r.SubcategoryName
-		r.Advertisement.Subcategory.Category = r.CategoryName
-		ads[i] = r.Advertisement
-		ads[i].PicturesText = make([]string, len(r.Pictures))
-		copy(ads[i].PicturesText, r.Pictures)
-	}
-
-	var formattedAds []map[string]interface{}
-
-	for _, ad := range ads {
-		user, exists := userMap[ad.User_id]
-		if !exists {
-			log.Printf("User with ID %d not found", ad.User_id)
-			continue
-		}
-
-		formattedAd := map[string]interface{}{
-			"id":          ad.ID,
-			"title":       ad.Title,
-			"price":       ad.Price,
-			"description": ad.Description,
-			"subcategory": map[string]interface{}{
-				"name":     ad.Subcategory.Name,
-				"category": ad.Subcategory.Category,
-			},
-			"user":     user,
-			"datetime": ad.Datetime,
-			"pictures": ad.PicturesText,
-			"location": ad.LocationText,
-		}
-		formattedAds = append(formattedAds, formattedAd)
-	}
+	respondWithAds(w, formatted)
+}
 
+func respondWithAds(w http.ResponseWriter, ads []map[string]interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(formattedAds); err != nil {
+	if err := json.NewEncoder(w).Encode(ads); err != nil {
 		log.Printf("Serialization error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
@@ -249,120 +279,38 @@ func GetNewestAds(w http.ResponseWriter, r *http.Request) {
 
 // GetClosestAds godoc
 // @Summary Get all ads ordered by distance from user's location
-// @Description Retrieves a list of all advertisements from near to far from user's location
+// @Description Retrieves a list of all active advertisements from near to far from user's location
 // @Tags advertisements
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {array} models.AdResponse "An array of advertisement objects"
+// @Failure 400 {object} string "Invalid user ID"
 // @Failure 500 {object} nil "Internal Server Error"
 // @Router /ads/{user_id}/nearest [get]
-func GetNearestAds(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	var result []ReadAd
-
-	err := models.DB.Raw(`
-       SELECT
-           a.*,
-           subcategories.id AS subcategory_id,
-           subcategories.name AS subcategory_name,
-           categories.name AS category_name,
-           ST_AsGeoJSON(a.location::geometry) AS location_text,
-           ST_Distance(
-              a.location,
-              (SELECT ST_SetSRID(ST_GeomFromEWKB(location), 4326) FROM users WHERE id = ?)
-           ) as distance
-      FROM advertisements a
-      JOIN subcategories ON subcategories.id = a.subcategory_id
-      JOIN categories ON categories.id = subcategories.category_id
-      ORDER BY distance ASC
-	    `, id).
-		Scan(&result).Error
+func (s *Server) GetNearestAds(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
 
+	ads, err := s.repos.Ads.NearestTo(r.Context(), id)
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	type user_result struct {
-		models.User
-		LocationText string `json:"location"`
-	}
-	var json_users []user_result
-
-	err = models.DB.Raw(`
-	    SELECT *, ST_AsGeoJSON(users.location::geometry) AS location_text
-	    FROM users`).
-		Scan(&json_users).Error
-
+	formatted, err := s.formatAds(r, ads)
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	users := make([]models.User, len(json_users))
-	for i, r := range json_users {
-		if r.User.LocationEWKB == nil {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-		} else {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		}
-		users[i] = r.User
-	}
-
-	userMap := make(map[uint]models.User)
-	for _, user := range users {
-		userMap[user.ID] = user
-	}
-
-	ads := make([]models.Advertisement, len(result))
-	for i, r := range result {
-		r.Advertisement.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		r.Advertisement.Subcategory.ID = r.SubcategoryID
-		r.Advertisement.Subcategory.Name = r.SubcategoryName
-		r.Advertisement.Subcategory.Category = r.CategoryName
-		ads[i] = r.Advertisement
-		ads[i].PicturesText = make([]string, len(r.Pictures))
-		copy(ads[i].PicturesText, r.Pictures)
-	}
-
-	var formattedAds []map[string]interface{}
-
-	for _, ad := range ads {
-		user, exists := userMap[ad.User_id]
-		if !exists {
-			log.Printf("User with ID %d not found", ad.User_id)
-			continue
-		}
-
-		formattedAd := map[string]interface{}{
-			"id":          ad.ID,
-			"title":       ad.Title,
-			"price":       ad.Price,
-			"description": ad.Description,
-			"subcategory": map[string]interface{}{
-				"name":     ad.Subcategory.Name,
-				"category": ad.Subcategory.Category,
-			},
-			"user":     user,
-			"datetime": ad.Datetime,
-			"pictures": ad.PicturesText,
-			"location": ad.LocationText,
-		}
-		formattedAds = append(formattedAds, formattedAd)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(formattedAds); err != nil {
-		log.Printf("Serialization error: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
-		return
-	}
+	respondWithAds(w, formatted)
 }
 
 // GetAd godoc
@@ -372,140 +320,132 @@ func GetNearestAds(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Ad ID"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {object} models.AdResponse "An advertisement object"
 // @Failure 404 {object} nil "Ad not found"
 // @Failure 500 {object} nil "Internal Server Error"
 // @Router /ads/{id} [get]
-func GetAd(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	var result ReadAd
-
-	err := models.DB.Raw(`
-	       SELECT
-	             advertisements.*,
-	             subcategories.id AS subcategory_id,
-	             subcategories.name AS subcategory_name,
-	             categories.name AS category_name,
-	             ST_AsGeoJSON(advertisements.location::geometry) AS location_text
-	       FROM advertisements
-	       JOIN subcategories ON subcategories.id = advertisements.subcategory_id
-	       JOIN categories ON categories.id = subcategories.category_id
-	       WHERE advertisements.id = ?
-	    `, id).
-		Scan(&result).Error
-
-	if result.Advertisement.ID == 0 {
+func (s *Server) GetAd(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
 		utils.RespondWithError(w, http.StatusNotFound, "Ad is not found")
 		return
 	}
 
-	result.Advertisement.LocationText = common.GeoJSONText{Data: json.RawMessage(result.LocationText)}
-	result.Advertisement.Subcategory.ID = result.SubcategoryID
-	result.Advertisement.Subcategory.Name = result.SubcategoryName
-	result.Advertisement.Subcategory.Category = result.CategoryName
-	ad := result.Advertisement
-	ad.PicturesText = make([]string, len(result.Pictures))
-	copy(ad.PicturesText, result.Pictures)
-
-	var user_json struct {
-		models.User
-		LocationText string `json:"location"`
-	}
-
-	err = models.DB.Raw(`
-	    SELECT users.*, ST_AsGeoJSON(users.location::geometry) AS location_text
-	    FROM users WHERE users.id = ?`, ad.User_id).
-		Scan(&user_json).Error
-
+	ad, err := s.repos.Ads.GetDetails(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "Ad is not found")
 		} else {
+			log.Printf("Request error: %v", err)
 			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		}
 		return
 	}
 
+	if !canSee(r, ad.Advertisement) {
+		utils.RespondWithError(w, http.StatusNotFound, "Ad is not found")
+		return
+	}
+
+	formatted, err := s.formatAds(r, []models.AdDetails{ad})
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	if user_json.User.LocationEWKB == nil {
-		user_json.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-	} else {
-		user_json.User.LocationText = common.GeoJSONText{Data: json.RawMessage(user_json.LocationText)}
-	}
-	user := user_json.User
-
-	formattedAd := map[string]interface{}{
-		"id":          ad.ID,
-		"title":       ad.Title,
-		"price":       ad.Price,
-		"description": ad.Description,
-		"subcategory": map[string]interface{}{
-			"name":     ad.Subcategory.Name,
-			"category": ad.Subcategory.Category,
-		},
-		"user":     user,
-		"datetime": ad.Datetime,
-		"pictures": ad.PicturesText,
-		"location": ad.LocationText,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(formattedAd); err != nil {
+	if err := json.NewEncoder(w).Encode(formatted[0]); err != nil {
 		log.Printf("Serialization error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 }
 
+// findAd loads the ad named by the id route variable and responds with
+// an error if it cannot.
+func (s *Server) findAd(w http.ResponseWriter, r *http.Request) (models.Advertisement, bool) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		return models.Advertisement{}, false
+	}
+
+	ad, err := s.repos.Ads.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return models.Advertisement{}, false
+	}
+
+	if !canSee(r, ad) {
+		utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		return models.Advertisement{}, false
+	}
+
+	return ad, true
+}
+
 type UserAdInput struct {
-	Title       string            `json:"title" validate:"required"`
-	Price       string            `json:"price" validate:"required"`
-	Subcategory string            `json:"subcategory" validate:"required"`
-	Category    string            `json:"category" validate:"required"`
-	Description string            `json:"description"`
-	UserEmail   string            `json:"user_email" validate:"required"`
-	Datetime    time.Time         `json:"datetime" validate:"required"`
-	Pictures    []string          `json:"pictures"`
-	Location    *geojson.Geometry `json:"location" validate:"required"`
+	Title         string            `json:"title" validate:"required"`
+	Price         models.Price      `json:"price"`
+	SubcategoryID uint              `json:"subcategory_id" validate:"required"`
+	Description   string            `json:"description"`
+	Datetime      time.Time         `json:"datetime" validate:"required"`
+	Location      *geojson.Geometry `json:"location" validate:"required"`
+	Status        string            `json:"status" validate:"omitempty,oneof=draft active"`
+	Attributes    models.Attributes `json:"attributes"`
+}
+
+// checkAttributes responds with an error unless the attributes fit the
+// subcategory's schema.
+func checkAttributes(w http.ResponseWriter, subcategory models.Subcategory, attributes models.Attributes) bool {
+	if err := subcategory.AttributeSchema.Validate(attributes); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid attributes: "+err.Error())
+		return false
+	}
+	return true
 }
 
 // CreateAd godoc
 // @Summary Add a new advertisement
-// @Description Adds a new advertisement with the given details
+// @Description Adds a new advertisement with the given details. Its attributes must fit the attribute schema of the subcategory.
 // @Tags advertisements
 // @Accept json
 // @Produce json
 // @Param ad body models.AdInput true "Create Ad"
 // @Success 200 {object} models.AdAdded "ID of the newly created ad"
-// @Failure 400 {string} string "Validation Error"
-// @Failure 404 {string} string "Subcategory/User is not found"
+// @Failure 400 {string} string "Validation Error or invalid attributes"
+// @Failure 404 {string} string "Subcategory is not found"
 // @Failure 403 {string} string "Failed to create a new ad"
 // @Failure 500 {string} string "Internal Server Error"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BearerAuth
 // @Router /ads [post]
-func CreateAd(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateAd(w http.ResponseWriter, r *http.Request) {
 	var (
 		locationEWKB []byte
 		geom         orb.Geometry
 		userInput    UserAdInput
-		subcategory  models.Subcategory
-		user         models.User
 	)
 
+	userID, _ := UserIDFromContext(r.Context())
+
 	body, _ := io.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &userInput)
 
-	validate = validator.New()
+	validate := validator.New()
+
+	userInput.Price = normalizePrice(userInput.Price)
 
 	err := validate.Struct(userInput)
-	if err != nil {
+	if err != nil || !hasCurrency(userInput.Price) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
 		return
 	}
@@ -515,42 +455,52 @@ func CreateAd(w http.ResponseWriter, r *http.Request) {
 		locationEWKB, err = orbToEWKB(geom, 4326)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, "Location Validation Error")
+			return
 		}
 	}
 
-	err = models.DB.
-		Preload("Category").
-		Where("name = ?", userInput.Subcategory).
-		First(&subcategory).Error
-	if subcategory.ID == 0 {
+	subcategory, err := s.repos.Subcategories.Get(r.Context(), userInput.SubcategoryID)
+	if err != nil {
 		utils.RespondWithError(w, http.StatusNotFound, "Subcategory is not found")
 		return
 	}
 
-	err = models.DB.
-		Where("email = ?", userInput.UserEmail).
-		First(&user).Error
-
-	if user.ID == 0 {
-		utils.RespondWithError(w, http.StatusNotFound, "User is not found")
+	if userInput.Attributes == nil {
+		userInput.Attributes = models.Attributes{}
+	}
+	if !checkAttributes(w, subcategory, userInput.Attributes) {
 		return
 	}
 
+	// The expiry follows the server clock; datetime comes from the client
+	// and would let it pick any expiry.
+	expiresAt := time.Now().Add(s.ads.TTL)
+
 	ad := &models.Advertisement{
 		Title:          userInput.Title,
 		Price:          userInput.Price,
 		Subcategory_id: subcategory.ID,
 		Description:    userInput.Description,
-		User_id:        user.ID,
+		User_id:        userID,
 		Datetime:       userInput.Datetime,
-		Pictures:       pq.StringArray(userInput.Pictures),
+		Pictures:       pq.StringArray{},
 		LocationEWKB:   locationEWKB,
+		Status:         userInput.Status,
+		ExpiresAt:      expiresAt,
+		Attributes:     userInput.Attributes,
+	}
+	if ad.Status == "" {
+		ad.Status = models.StatusActive
 	}
 
-	if err := models.DB.Create(ad).Error; err != nil {
+	if err := s.repos.Ads.Create(r.Context(), ad); err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Failed to create a new ad")
 		return
 	}
+	s.auditAd(r, ad.ID, models.AuditCreate, nil)
+	if ad.Status == models.StatusActive {
+		s.matcher.Wake()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -559,29 +509,33 @@ func CreateAd(w http.ResponseWriter, r *http.Request) {
 
 // UpdateAd godoc
 // @Summary Update an advertisement
-// @Description Update an existing advertisement by its ID with new information
+// @Description Update an existing advertisement by its ID with new information. Its attributes must fit the attribute schema of the subcategory.
 // @Tags advertisements
 // @Accept json
 // @Produce json
 // @Param id path int true "Ad ID"
 // @Param ad body models.AdInput true "Advertisement data"
 // @Success 200 {object} models.AdResponse "Successfully updated advertisement"
-// @Failure 400 {object} string "Validation Error"
-// @Failure 403 {object} string "Failed to update the ad"
-// @Failure 404 {object} string "Ad/Subcategory/User not found"
+// @Failure 400 {object} string "Validation Error or invalid attributes"
+// @Failure 403 {object} string "Not the owner of the ad or failed to update it"
+// @Failure 404 {object} string "Ad/Subcategory not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BearerAuth
 // @Router /ads/{id} [put]
-func UpdateAd(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateAd(w http.ResponseWriter, r *http.Request) {
 	var (
 		locationEWKB []byte
 		geom         orb.Geometry
-		ad           models.Advertisement
 		userInput    UserAdInput
-		user         models.User
 	)
 
-	id := mux.Vars(r)["id"]
-	if err := models.DB.Where("id = ?", id).First(&ad).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
 		return
 	}
 
@@ -589,9 +543,11 @@ func UpdateAd(w http.ResponseWriter, r *http.Request) {
 	_ = json.Unmarshal(body, &userInput)
 
 	validate := validator.New()
+	userInput.Price = normalizePrice(userInput.Price)
+
 	err := validate.Struct(userInput)
 
-	if err != nil {
+	if err != nil || !hasCurrency(userInput.Price) {
 		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
 		return
 	}
@@ -601,25 +557,27 @@ func UpdateAd(w http.ResponseWriter, r *http.Request) {
 		locationEWKB, err = orbToEWKB(geom, 4326)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, "Location Validation Error")
+			return
 		}
 	}
 
-	var subcategory models.Subcategory
-	err = models.DB.
-		Preload("Category").
-		Where("name = ?", userInput.Subcategory).
-		First(&subcategory).Error
-	if subcategory.ID == 0 {
+	subcategory, err := s.repos.Subcategories.Get(r.Context(), userInput.SubcategoryID)
+	if err != nil {
 		utils.RespondWithError(w, http.StatusNotFound, "Subcategory is not found")
 		return
 	}
 
-	err = models.DB.
-		Where("email = ?", userInput.UserEmail).
-		First(&user).Error
+	if userInput.Attributes == nil {
+		userInput.Attributes = models.Attributes{}
+	}
+	if !checkAttributes(w, subcategory, userInput.Attributes) {
+		return
+	}
 
-	if user.ID == 0 {
-		utils.RespondWithError(w, http.StatusNotFound, "User is not found")
+	before, err := s.loadAdRecord(r, ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
@@ -627,15 +585,16 @@ func UpdateAd(w http.ResponseWriter, r *http.Request) {
 	ad.Price = userInput.Price
 	ad.Subcategory_id = subcategory.ID
 	ad.Description = userInput.Description
-	ad.User_id = user.ID
 	ad.Datetime = userInput.Datetime
-	ad.Pictures = userInput.Pictures
 	ad.LocationEWKB = locationEWKB
+	ad.Attributes = userInput.Attributes
 
-	if err := models.DB.Save(&ad).Error; err != nil {
+	if err := s.repos.Ads.Update(r.Context(), &ad); err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Failed to update the ad")
 		return
 	}
+	s.auditAd(r, ad.ID, models.AuditUpdate, before)
+	s.publishAdEvent(r, events.AdUpdated, ad.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -644,25 +603,118 @@ func UpdateAd(w http.ResponseWriter, r *http.Request) {
 
 // DeleteAd godoc
 // @Summary Delete an advertisement
-// @Description Deletes an advertisement by its ID
+// @Description Soft-deletes an advertisement by its ID. Its pictures, bookmarks and conversations are kept so that an admin can restore it.
 // @Tags advertisements
 // @Accept json
 // @Produce json
 // @Param id path int true "Ad ID"
 // @Success 200 {string} string "Ad deleted successfully"
+// @Failure 403 {object} string "Not the owner of the ad"
 // @Failure 404 {object} string "Ad not found"
+// @Failure 401 {string} string "Unauthorized"
+// @Security BearerAuth
 // @Router /ads/{id} [delete]
-func DeleteAd(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var ad models.Advertisement
+func (s *Server) DeleteAd(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
 
-	if err := models.DB.Where("id = ?", id).First(&ad).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	before, err := s.loadAdRecord(r, ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	models.DB.Delete(&ad)
+	if err := s.repos.Ads.Delete(r.Context(), ad.ID); err != nil {
+		log.Printf("Error deleting ad: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityAd, ad.ID, models.AuditDelete, before, nil)
+	s.publishAdEvent(r, events.AdDeleted, ad.ID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+// RestoreAd godoc
+// @Summary Restore a deleted advertisement
+// @Description Brings back a deleted advertisement with its status and expiry unchanged. Its subcategory and owner must not be deleted.
+// @Tags advertisements
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Success 204 "Ad restored"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Deleted ad not found"
+// @Failure 409 {object} string "The subcategory or the owner of the ad is deleted"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/restore [post]
+func (s *Server) RestoreAd(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Deleted ad not found")
+		return
+	}
+
+	if err := s.repos.Ads.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Deleted ad not found")
+		case errors.Is(err, repository.ErrForeignKey):
+			utils.RespondWithError(w, http.StatusConflict, "The subcategory or the owner of the ad is deleted")
+		default:
+			log.Printf("Error restoring ad: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.auditAd(r, id, models.AuditRestore, nil)
+	s.publishAdEvent(r, events.AdUpdated, id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func isAdOwner(r *http.Request, ad models.Advertisement) bool {
+	userID, ok := UserIDFromContext(r.Context())
+	return ok && ad.User_id == userID
+}
+
+// canSee hides drafts, pending ads and the ads moderators rejected or hid
+// from everyone but their owner and moderators.
+func canSee(r *http.Request, ad models.Advertisement) bool {
+	return !models.IsPrivateStatus(ad.Status) || isAdOwner(r, ad) || isModerator(r)
+}
+
+// canListStatus allows listing private statuses only to moderators or
+// when the listing is restricted to the caller's own ads.
+func canListStatus(r *http.Request, filter repository.AdFilter) bool {
+	if !models.IsPrivateStatus(filter.Status) || isModerator(r) {
+		return true
+	}
+	userID, ok := UserIDFromContext(r.Context())
+	return ok && filter.UserID == userID
+}
+
+// normalizePrice upper-cases the currency code and drops the amount and
+// currency of free ads.
+func normalizePrice(price models.Price) models.Price {
+	price.Currency = strings.ToUpper(price.Currency)
+	if price.Free {
+		price.Amount = 0
+		price.Currency = ""
+	}
+	return price
+}
+
+func hasCurrency(price models.Price) bool {
+	return price.Free || price.Currency != ""
+}
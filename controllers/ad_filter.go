@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// parseAdFilter reads the query string parameters accepted by ad
+// listings.
+func parseAdFilter(query url.Values) (repository.AdFilter, error) {
+	filter := repository.AdFilter{Limit: defaultPageSize}
+
+	var err error
+	parseUint := func(name string) uint {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return 0
+		}
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 0)
+		return uint(n)
+	}
+	parseInt := func(name string) *int64 {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return nil
+		}
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 64)
+		return &n
+	}
+	parseTime := func(name string) *time.Time {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return nil
+		}
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, value)
+		return &t
+	}
+
+	if limit := parseUint("limit"); limit != 0 {
+		filter.Limit = int(min(limit, maxPageSize))
+	}
+	filter.Cursor = parseUint("cursor")
+	filter.Offset = int(parseUint("offset"))
+	filter.CategoryID = parseUint("category_id")
+	filter.SubcategoryID = parseUint("subcategory_id")
+	filter.PriceMin = parseInt("price_min")
+	filter.PriceMax = parseInt("price_max")
+	filter.Currency = strings.ToUpper(query.Get("currency"))
+	filter.From = parseTime("from")
+	filter.To = parseTime("to")
+	filter.UserID = parseUint("user_id")
+	filter.Query = strings.TrimSpace(query.Get("q"))
+	filter.Sort = query.Get("sort")
+	filter.Status = query.Get("status")
+	if filter.Status == "" {
+		filter.Status = models.StatusActive
+	}
+
+	if err != nil {
+		return filter, err
+	}
+
+	if filter.Cursor != 0 && filter.Offset != 0 {
+		return filter, errors.New("cursor and offset are mutually exclusive")
+	}
+
+	if filter.Cursor != 0 && (filter.Query != "" || filter.Sort != "") {
+		return filter, errors.New("sorted results are paged with offset")
+	}
+
+	if filter.Sort != "" && filter.Sort != repository.SortPriceAsc && filter.Sort != repository.SortPriceDesc {
+		return filter, errors.New("unknown sort order")
+	}
+
+	if !models.IsValidStatus(filter.Status) {
+		return filter, errors.New("unknown status")
+	}
+
+	filter.Attributes, err = parseAttributeFilters(query)
+	if err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// attributePrefix starts the query string parameters that filter by
+// attributes: attr.<name> for a value, attr.<name>.min and
+// attr.<name>.max for a numeric range.
+const attributePrefix = "attr."
+
+func parseAttributeFilters(query url.Values) ([]repository.AttributeFilter, error) {
+	filters := make(map[string]*repository.AttributeFilter)
+	for key := range query {
+		rest, ok := strings.CutPrefix(key, attributePrefix)
+		if !ok {
+			continue
+		}
+		name, bound, _ := strings.Cut(rest, ".")
+		if !models.IsValidAttributeName(name) {
+			return nil, errors.New("invalid attribute name")
+		}
+		f, ok := filters[name]
+		if !ok {
+			f = &repository.AttributeFilter{Name: name}
+			filters[name] = f
+		}
+
+		value := query.Get(key)
+		switch bound {
+		case "":
+			f.Value = value
+		case "min", "max":
+			n, err := parseFinite(value)
+			if err != nil {
+				return nil, err
+			}
+			if bound == "min" {
+				f.Min = &n
+			} else {
+				f.Max = &n
+			}
+		default:
+			return nil, errors.New("unknown attribute filter")
+		}
+	}
+
+	result := make([]repository.AttributeFilter, 0, len(filters))
+	for _, f := range filters {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// parsePage reads the limit and offset parameters of listings that are
+// paged by offset only.
+func parsePage(query url.Values) (limit, offset int, err error) {
+	limit = defaultPageSize
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return 0, 0, err
+		}
+		if n != 0 {
+			limit = int(min(n, maxPageSize))
+		}
+	}
+	if value := query.Get("offset"); value != "" {
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			return 0, 0, err
+		}
+		offset = int(n)
+	}
+	return limit, offset, nil
+}
+
+const maxSearchRadius = 1000000
+
+func parseGeoFilter(query url.Values) (repository.GeoFilter, error) {
+	var filter repository.GeoFilter
+
+	if value := query.Get("bbox"); value != "" {
+		parts := strings.Split(value, ",")
+		if len(parts) != 4 {
+			return filter, errors.New("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		var bbox [4]float64
+		for i, part := range parts {
+			n, err := parseFinite(strings.TrimSpace(part))
+			if err != nil {
+				return filter, err
+			}
+			bbox[i] = n
+		}
+		if !validLonLat(bbox[0], bbox[1]) || !validLonLat(bbox[2], bbox[3]) ||
+			bbox[0] >= bbox[2] || bbox[1] >= bbox[3] {
+			return filter, errors.New("invalid bbox")
+		}
+		filter.BBox = &bbox
+		filter.Lon = (bbox[0] + bbox[2]) / 2
+		filter.Lat = (bbox[1] + bbox[3]) / 2
+	}
+
+	lat, lon, radius := query.Get("lat"), query.Get("lon"), query.Get("radius")
+	if lat == "" && lon == "" && radius == "" {
+		if filter.BBox == nil {
+			return filter, errors.New("either lat, lon and radius or bbox is required")
+		}
+		return filter, nil
+	}
+
+	var err error
+	if filter.Lat, err = parseFinite(lat); err != nil {
+		return filter, err
+	}
+	if filter.Lon, err = parseFinite(lon); err != nil {
+		return filter, err
+	}
+	if !validLonLat(filter.Lon, filter.Lat) {
+		return filter, errors.New("invalid coordinates")
+	}
+
+	if radius != "" {
+		if filter.Radius, err = parseFinite(radius); err != nil {
+			return filter, err
+		}
+		if filter.Radius <= 0 || filter.Radius > maxSearchRadius {
+			return filter, errors.New("invalid radius")
+		}
+	} else if filter.BBox == nil {
+		return filter, errors.New("radius is required")
+	}
+
+	return filter, nil
+}
+
+// parseFinite parses a float, rejecting the NaN and infinities that
+// strconv.ParseFloat accepts.
+func parseFinite(value string) (float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return 0, errors.New("number must be finite")
+	}
+	return n, nil
+}
+
+func validLonLat(lon, lat float64) bool {
+	return lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+// formatAttributeFilters renders filters as name=value[min,max] for
+// comparison, with unset bounds left empty.
+func formatAttributeFilters(filters []repository.AttributeFilter) string {
+	bound := func(n *float64) string {
+		if n == nil {
+			return ""
+		}
+		return fmt.Sprint(*n)
+	}
+	result := ""
+	for _, f := range filters {
+		result += fmt.Sprintf("%s=%s[%s,%s] ", f.Name, f.Value, bound(f.Min), bound(f.Max))
+	}
+	return result
+}
+
+func TestParseAttributeFilters(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"q=spade&price_min=100", "", false},
+		{"attr.state=used", "state=used[,] ", false},
+		{"attr.state=", "state=[,] ", false},
+		{"attr.power_w.min=500&attr.power_w.max=1000.5", "power_w=[500,1000.5] ", false},
+		{"attr.power_w.max=-1e3", "power_w=[,-1000] ", false},
+		{"attr.year.min=2000&attr.year=2020", "year=2020[2000,] ", false},
+		{"attr.year=2020&attr.brand=Bosch", "brand=Bosch[,] year=2020[,] ", false},
+		{"attr.Brand=Bosch", "", true},
+		{"attr.=Bosch", "", true},
+		{"attr.power_w.avg=500", "", true},
+		{"attr.power_w.min.x=500", "", true},
+		{"attr.power_w.min=", "", true},
+		{"attr.power_w.min=many", "", true},
+		{"attr.power_w.min=NaN", "", true},
+		{"attr.power_w.max=Inf", "", true},
+		{"attr.power_w.max=-infinity", "", true},
+		{"attr.power_w.min=1e400", "", true},
+	}
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filters, err := parseAttributeFilters(query)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseAttributeFilters(%q) error = %v, want error %v", test.query, err, test.wantErr)
+			continue
+		}
+		if got := formatAttributeFilters(filters); err == nil && got != test.want {
+			t.Errorf("parseAttributeFilters(%q) = %q, want %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestParseGeoFilterRejectsNonFinite(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"lat=52.5&lon=13.4&radius=1000", false},
+		{"bbox=13,52,14,53", false},
+		{"lat=NaN&lon=13.4&radius=1000", true},
+		{"lat=52.5&lon=Inf&radius=1000", true},
+		{"lat=52.5&lon=13.4&radius=NaN", true},
+		{"lat=52.5&lon=13.4&radius=+Inf", true},
+		{"bbox=13,52,14,NaN", true},
+		{"bbox=-Inf,52,14,53", true},
+	}
+	for _, test := range tests {
+		query, err := url.ParseQuery(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := parseGeoFilter(query); (err != nil) != test.wantErr {
+			t.Errorf("parseGeoFilter(%q) error = %v, want error %v", test.query, err, test.wantErr)
+		}
+	}
+}
+
+func TestNonFiniteFiltersAreBadRequests(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, path := range []string{
+		"/ads?attr.power_w.min=NaN",
+		"/ads/search?attr.power_w.max=Inf&lat=52.5&lon=13.4&radius=1000",
+		"/ads/search?lat=52.5&lon=13.4&radius=NaN",
+	} {
+		expectStatus(t, ts.do("GET", path, "", nil), http.StatusBadRequest)
+	}
+}
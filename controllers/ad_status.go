@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+const defaultAdTTL = 30 * 24 * time.Hour
+
+// AdConfig holds the ad settings read from the environment: AD_TTL is
+// how long an ad stays active after it is created or last renewed
+// (a Go duration such as "720h").
+type AdConfig struct {
+	TTL time.Duration
+}
+
+func LoadAdConfig() (AdConfig, error) {
+	config := AdConfig{TTL: defaultAdTTL}
+
+	if value := os.Getenv("AD_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid AD_TTL: %w", err)
+		}
+		if ttl <= 0 {
+			return config, errors.New("AD_TTL must be positive")
+		}
+		config.TTL = ttl
+	}
+
+	return config, nil
+}
+
+type AdStatusInput struct {
+	Status string `json:"status" validate:"required,oneof=draft active reserved sold"`
+}
+
+// UpdateAdStatus godoc
+// @Summary Change the status of an advertisement
+// @Description Moves an ad through its lifecycle. Owners can publish a draft, take an active ad back to draft, reserve it, mark it sold, release a reservation, and take an expired ad back to draft. Owners can also take an ad a moderator rejected back to draft to fix it; publishing that draft makes it pending until a moderator approves it. Sold and hidden ads cannot change status, pending ads can only go back to draft, and expired ads become active again only by renewal.
+// @Tags advertisements
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param status body AdStatusInput true "New status"
+// @Success 200 {object} models.AdStatus "The new status"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Not the owner of the ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 409 {object} string "The ad cannot move to the status"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/status [put]
+func (s *Server) UpdateAdStatus(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	var input AdStatusInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	if !models.CanChangeStatus(ad.Status, input.Status) {
+		utils.RespondWithError(w, http.StatusConflict,
+			fmt.Sprintf("Cannot change the status from %s to %s", ad.Status, input.Status))
+		return
+	}
+
+	if input.Status == models.StatusActive && !ad.ExpiresAt.After(time.Now()) {
+		utils.RespondWithError(w, http.StatusConflict, "The ad has expired, renew it instead")
+		return
+	}
+
+	status := input.Status
+	if ad.Status == models.StatusDraft {
+		var err error
+		if status, err = s.publishedStatus(r, ad.ID); err != nil {
+			log.Printf("Error loading moderation actions: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+	}
+
+	before, err := s.loadAdRecord(r, ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if err := s.repos.Ads.SetStatus(r.Context(), ad.ID, ad.Status, status); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusConflict, "The ad status has changed, try again")
+		} else {
+			log.Printf("Error changing ad status: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.auditAd(r, ad.ID, models.AuditUpdate, before)
+
+	s.publishAdEvent(r, events.AdUpdated, ad.ID)
+	if status == models.StatusActive {
+		s.matcher.Wake()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.AdStatus{ID: ad.ID, Status: status, ExpiresAt: ad.ExpiresAt})
+}
+
+// publishedStatus returns the status the draft moves to when its owner
+// publishes it, which depends on the last moderation action on the ad.
+func (s *Server) publishedStatus(r *http.Request, adID uint) (string, error) {
+	actions, err := s.repos.Moderation.Actions(r.Context(), adID)
+	if err != nil {
+		return "", err
+	}
+	lastAction := ""
+	if len(actions) > 0 {
+		lastAction = actions[0].Action
+	}
+	return models.PublishedStatus(lastAction), nil
+}
+
+// RenewAd godoc
+// @Summary Renew an advertisement
+// @Description Keeps an ad listed for another AD_TTL from now. An expired ad becomes active again; drafts stay drafts. Reserved and sold ads cannot be renewed.
+// @Tags advertisements
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Success 200 {object} models.AdStatus "The status and new expiry"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Not the owner of the ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 409 {object} string "The ad cannot be renewed"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/renew [post]
+func (s *Server) RenewAd(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	before, err := s.loadAdRecord(r, ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	until := time.Now().Add(s.ads.TTL)
+	if err := s.repos.Ads.Renew(r.Context(), ad.ID, until); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusConflict,
+				fmt.Sprintf("Cannot renew an ad that is %s", ad.Status))
+		} else {
+			log.Printf("Error renewing ad: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.auditAd(r, ad.ID, models.AuditUpdate, before)
+
+	status := ad.Status
+	if status == models.StatusExpired {
+		status = models.StatusActive
+		s.publishAdEvent(r, events.AdUpdated, ad.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.AdStatus{ID: ad.ID, Status: status, ExpiresAt: until})
+}
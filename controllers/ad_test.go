@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+// titles lists the titles of the ads on a page returned by GET /ads or
+// GET /ads/search, sorted.
+func titles(t *testing.T, ts *testServer, path, token string) []string {
+	t.Helper()
+
+	rec := ts.do("GET", path, token, nil)
+	expectStatus(t, rec, http.StatusOK)
+	var page struct {
+		Items []struct {
+			Title string `json:"title"`
+		} `json:"items"`
+	}
+	decode(t, rec, &page)
+
+	result := []string{}
+	for _, ad := range page.Items {
+		result = append(result, ad.Title)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestListAdsFilters(t *testing.T) {
+	ts := newTestServer(t)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	_, other := ts.user("other", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+	seeds := ts.subcategory("Plants", "Seeds")
+
+	ts.ad(seller, adInput("Rusty spade", tools, 500))
+	ts.ad(seller, adInput("Tomato seeds", seeds, 150))
+	free := adInput("Old rake", tools, 0)
+	free["price"] = map[string]interface{}{"free": true}
+	ts.ad(seller, free)
+	ts.ad(other, adInput("Garden hose", tools, 2500))
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Garden hose", "Old rake", "Rusty spade", "Tomato seeds"}},
+		{fmt.Sprintf("subcategory_id=%d", seeds), []string{"Tomato seeds"}},
+		{"price_min=200", []string{"Garden hose", "Rusty spade"}},
+		{"price_max=1000", []string{"Old rake", "Rusty spade", "Tomato seeds"}},
+		{"currency=usd", []string{"Old rake"}},
+		{fmt.Sprintf("user_id=%d", sellerID), []string{"Old rake", "Rusty spade", "Tomato seeds"}},
+		{"q=spade", []string{"Rusty spade"}},
+		{"status=sold", []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			got := titles(t, ts, "/ads?"+test.query, "")
+			if !slices.Equal(got, test.want) {
+				t.Errorf("GET /ads?%s = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+
+	expectStatus(t, ts.do("GET", "/ads?sort=cheapest", "", nil), http.StatusBadRequest)
+	expectStatus(t, ts.do("GET", "/ads?cursor=1&offset=1", "", nil), http.StatusBadRequest)
+}
+
+func TestAdChangesRequireOwner(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	_, other := ts.user("other", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+	id := ts.ad(seller, adInput("Rusty spade", tools, 500))
+	path := fmt.Sprintf("/ads/%d", id)
+
+	expectStatus(t, ts.do("PUT", path, other, adInput("Shiny spade", tools, 900)), http.StatusForbidden)
+	expectStatus(t, ts.do("DELETE", path, other, nil), http.StatusForbidden)
+
+	expectStatus(t, ts.do("PUT", path, seller, adInput("Shiny spade", tools, 900)), http.StatusOK)
+	expectStatus(t, ts.do("DELETE", path, seller, nil), http.StatusOK)
+	expectStatus(t, ts.do("GET", path, "", nil), http.StatusNotFound)
+}
+
+func TestDraftsAreHiddenFromOthers(t *testing.T) {
+	ts := newTestServer(t)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	_, other := ts.user("other", models.RoleUser)
+	_, moderator := ts.user("moderator", models.RoleModerator)
+	tools := ts.subcategory("Garden", "Tools")
+	draft := adInput("Rusty spade", tools, 500)
+	draft["status"] = models.StatusDraft
+	path := fmt.Sprintf("/ads/%d", ts.ad(seller, draft))
+
+	expectStatus(t, ts.do("GET", path, "", nil), http.StatusNotFound)
+	expectStatus(t, ts.do("GET", path, other, nil), http.StatusNotFound)
+	expectStatus(t, ts.do("GET", path, seller, nil), http.StatusOK)
+	expectStatus(t, ts.do("GET", path, moderator, nil), http.StatusOK)
+
+	if got := titles(t, ts, "/ads", ""); len(got) != 0 {
+		t.Errorf("GET /ads lists drafts: %q", got)
+	}
+	expectStatus(t, ts.do("GET", "/ads?status=draft", other, nil), http.StatusForbidden)
+	mine := fmt.Sprintf("/ads?status=draft&user_id=%d", sellerID)
+	if got := titles(t, ts, mine, seller); !slices.Equal(got, []string{"Rusty spade"}) {
+		t.Errorf("GET %s = %q", mine, got)
+	}
+}
+
+// TestSavedSearchesMatchListings checks that the ads a saved search is
+// notified of are the ones GET /ads lists for the same filters.
+func TestSavedSearchesMatchListings(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	buyerID, _ := ts.user("buyer", models.RoleUser)
+	_, seller := ts.user("seller", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+	seeds := ts.subcategory("Plants", "Seeds")
+
+	priceMin, priceMax := int64(200), int64(1000)
+	searches := []struct {
+		search models.SavedSearch
+		query  string
+	}{
+		{models.SavedSearch{SubcategoryID: &tools}, fmt.Sprintf("subcategory_id=%d", tools)},
+		{models.SavedSearch{PriceMin: &priceMin}, "price_min=200"},
+		{models.SavedSearch{PriceMax: &priceMax}, "price_max=1000"},
+		{models.SavedSearch{Currency: "EUR"}, "currency=EUR"},
+		{models.SavedSearch{Query: "spade"}, "q=spade"},
+	}
+	for i := range searches {
+		searches[i].search.UserID = buyerID
+		searches[i].search.Name = searches[i].query
+		if err := ts.repos.SavedSearches.Create(ctx, &searches[i].search); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	free := adInput("Old rake", tools, 0)
+	free["price"] = map[string]interface{}{"free": true}
+	titleOf := make(map[uint]string)
+	for _, input := range []map[string]interface{}{
+		adInput("Rusty spade", tools, 500),
+		adInput("Tomato seeds", seeds, 150),
+		adInput("Garden hose", tools, 2500),
+		free,
+	} {
+		id := ts.ad(seller, input)
+		titleOf[id] = input["title"].(string)
+		if err := ts.matcher.Match(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notifications, err := ts.repos.Notifications.List(ctx, buyerID, 100, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range searches {
+		matched := []string{}
+		for _, n := range notifications {
+			if n.SavedSearchID == s.search.ID {
+				matched = append(matched, titleOf[n.AdID])
+			}
+		}
+		sort.Strings(matched)
+
+		if listed := titles(t, ts, "/ads?"+s.query, ""); !slices.Equal(matched, listed) {
+			t.Errorf("saved search %s matched %q, GET /ads lists %q", s.query, matched, listed)
+		}
+	}
+}
+
+func TestAdExpiryFollowsServerClock(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	input := adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500)
+	input["datetime"] = "2100-01-01T00:00:00Z"
+
+	before := time.Now()
+	id := ts.ad(seller, input)
+
+	ad, err := ts.repos.Ads.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ad.ExpiresAt.Before(before.Add(defaultAdTTL)) || ad.ExpiresAt.After(time.Now().Add(defaultAdTTL)) {
+		t.Fatalf("ad expires at %v, want %v from now", ad.ExpiresAt, defaultAdTTL)
+	}
+}
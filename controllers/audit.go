@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+// audit records a write in the audit log on behalf of the signed in user.
+// before and after are the record as it was and as it is now; before is
+// nil for a create and after for a delete. The write has already
+// happened, so failures are only logged.
+func (s *Server) audit(r *http.Request, entity string, id uint, action string, before, after interface{}) {
+	var actorID *uint
+	if userID, ok := UserIDFromContext(r.Context()); ok {
+		actorID = &userID
+	}
+	s.auditAs(r, actorID, entity, id, action, before, after)
+}
+
+// auditAs is audit for writes made by someone who is not signed in yet,
+// such as a user registering.
+func (s *Server) auditAs(r *http.Request, actorID *uint, entity string, id uint, action string, before, after interface{}) {
+	changes, err := models.Diff(before, after)
+	if err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+		return
+	}
+
+	entry := models.AuditEntry{
+		ActorID:   actorID,
+		Entity:    entity,
+		EntityID:  id,
+		Action:    action,
+		Changes:   changes,
+		RequestID: RequestIDFromContext(r.Context()),
+	}
+	if err := s.repos.Audit.Record(r.Context(), &entry); err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+	}
+}
+
+// adRecord is what the audit log keeps of an ad: its JSON form with the
+// location filled in, plus the picture URLs that form leaves out.
+type adRecord struct {
+	models.Advertisement
+	Pictures []string `json:"pictures"`
+}
+
+// loadAdRecord loads the ad as the audit log records it.
+func (s *Server) loadAdRecord(r *http.Request, id uint) (interface{}, error) {
+	ad, err := s.repos.Ads.GetDetails(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return adRecord{Advertisement: ad.Advertisement, Pictures: ad.PicturesText}, nil
+}
+
+// auditAd records a write that left the ad in place, comparing it as it
+// is now with before, a record from loadAdRecord or nil.
+func (s *Server) auditAd(r *http.Request, id uint, action string, before interface{}) {
+	after, err := s.loadAdRecord(r, id)
+	if err != nil {
+		log.Printf("Error recording audit entry: %v", err)
+		return
+	}
+	s.audit(r, models.EntityAd, id, action, before, after)
+}
+
+// auditDeleted records the delete of every record that went together
+// with the one a request deleted, each in an entry of its own.
+func (s *Server) auditDeleted(r *http.Request, deleted repository.Deleted) {
+	for _, category := range deleted.Categories {
+		s.audit(r, models.EntityCategory, category.ID, models.AuditDelete, category, nil)
+	}
+	for _, subcategory := range deleted.Subcategories {
+		s.audit(r, models.EntitySubcategory, subcategory.ID, models.AuditDelete, subcategory, nil)
+	}
+	for _, ad := range deleted.Ads {
+		before := adRecord{Advertisement: ad.Advertisement, Pictures: ad.PicturesText}
+		s.audit(r, models.EntityAd, ad.ID, models.AuditDelete, before, nil)
+	}
+}
+
+// pictureRecord is what the audit log keeps of an ad when only its
+// pictures change.
+func pictureRecord(pictures []string) interface{} {
+	return map[string][]string{"pictures": append([]string{}, pictures...)}
+}
+
+// favoriteRecord is what the audit log keeps of a favorite.
+func favoriteRecord(userID, adID uint) interface{} {
+	return map[string]uint{"user_id": userID, "ad_id": adID}
+}
+
+// messageRecord is what the audit log keeps of a message. The body is
+// private to the conversation and left out.
+func messageRecord(message models.Message) interface{} {
+	return map[string]uint{
+		"id":              message.ID,
+		"conversation_id": message.ConversationID,
+		"sender_id":       message.SenderID,
+	}
+}
+
+// readRecord is what the audit log keeps of marking a conversation read:
+// how many messages were marked and the range of their IDs.
+func readRecord(messages []models.Message) interface{} {
+	record := map[string]uint{"messages_read": uint(len(messages))}
+	for i, message := range messages {
+		if i == 0 || message.ID < record["first_message_id"] {
+			record["first_message_id"] = message.ID
+		}
+		if message.ID > record["last_message_id"] {
+			record["last_message_id"] = message.ID
+		}
+	}
+	return record
+}
+
+// parseAuditFilter reads the query string parameters accepted by the
+// audit log.
+func parseAuditFilter(query url.Values) (repository.AuditFilter, error) {
+	var filter repository.AuditFilter
+
+	var err error
+	filter.Limit, filter.Offset, err = parsePage(query)
+	if err != nil {
+		return filter, err
+	}
+
+	parseUint := func(name string) uint {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return 0
+		}
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 0)
+		return uint(n)
+	}
+	parseTime := func(name string) *time.Time {
+		value := query.Get(name)
+		if value == "" || err != nil {
+			return nil
+		}
+		var t time.Time
+		t, err = time.Parse(time.RFC3339, value)
+		return &t
+	}
+
+	filter.ActorID = parseUint("actor_id")
+	filter.Entity = query.Get("entity")
+	filter.EntityID = parseUint("entity_id")
+	filter.Action = query.Get("action")
+	filter.RequestID = query.Get("request_id")
+	filter.From = parseTime("from")
+	filter.To = parseTime("to")
+
+	return filter, err
+}
+
+// GetAuditLog godoc
+// @Summary Get the audit log
+// @Description Retrieves a page of the audit log, newest first. Every create, update, delete and restore of an ad, user, category, subcategory, saved search or report is recorded with the user who made it, the fields it changed and the ID of the request, which responses return in the X-Request-ID header. So are favorites added and removed, under the ID of the ad, conversations started, messages posted, without their text, conversations read, with the number and ID range of the messages read, and notifications read. Records deleted together with a category, subcategory or user get an entry each, while a restore is recorded only for the record restored. Ads that expire are recorded without a user or request ID. An entry is written after the change it records, so a change whose entry cannot be written still stands; the failure is only logged.
+// @Tags audit
+// @Produce json
+// @Param actor_id query int false "Only writes made by this user"
+// @Param entity query string false "Only writes to this kind of record" Enums(ad, user, category, subcategory, saved_search, report, favorite, conversation, message, notification)
+// @Param entity_id query int false "Only writes to the record with this ID"
+// @Param action query string false "Only this kind of write" Enums(create, update, delete, restore)
+// @Param request_id query string false "Only writes made by this request"
+// @Param from query string false "Only writes made at or after this time (RFC 3339)"
+// @Param to query string false "Only writes made at or before this time (RFC 3339)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of entries to skip"
+// @Success 200 {object} models.AuditLog "A page of audit entries"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /audit [get]
+func (s *Server) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	page, err := s.repos.Audit.List(r.Context(), filter)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if page.Entries == nil {
+		page.Entries = []models.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.AuditLog{
+		Items: page.Entries,
+		Pagination: models.Pagination{
+			Limit:   filter.Limit,
+			Offset:  filter.Offset,
+			Total:   page.Total,
+			HasMore: page.HasMore,
+		},
+	})
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+// auditLog lists the entries matching the filter as "entity id action",
+// oldest first.
+func auditLog(t *testing.T, ts *testServer, filter repository.AuditFilter) []string {
+	t.Helper()
+
+	filter.Limit = 100
+	page, err := ts.repos.Audit.List(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := []string{}
+	for i := len(page.Entries) - 1; i >= 0; i-- {
+		entry := page.Entries[i]
+		result = append(result, fmt.Sprintf("%s %d %s", entry.Entity, entry.EntityID, entry.Action))
+	}
+	return result
+}
+
+func TestAuditRecordsFavorites(t *testing.T) {
+	ts := newTestServer(t)
+	buyerID, buyer := ts.user("buyer", models.RoleUser)
+	_, seller := ts.user("seller", models.RoleUser)
+	ad := ts.ad(seller, adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500))
+
+	favorites := fmt.Sprintf("/users/%d/favorites", buyerID)
+	expectStatus(t, ts.do("POST", favorites, buyer, FavoriteInput{AdID: ad}), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", favorites, buyer, FavoriteInput{AdID: ad}), http.StatusNoContent)
+	expectStatus(t, ts.do("DELETE", fmt.Sprintf("%s/%d", favorites, ad), buyer, nil), http.StatusNoContent)
+
+	got := auditLog(t, ts, repository.AuditFilter{Entity: models.EntityFavorite, ActorID: buyerID})
+	want := []string{fmt.Sprintf("favorite %d create", ad), fmt.Sprintf("favorite %d delete", ad)}
+	if !slices.Equal(got, want) {
+		t.Errorf("audit log %q, want %q", got, want)
+	}
+}
+
+func TestAuditRecordsConversations(t *testing.T) {
+	ts := newTestServer(t)
+	buyerID, buyer := ts.user("buyer", models.RoleUser)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	ad := ts.ad(seller, adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500))
+
+	start := fmt.Sprintf("/ads/%d/conversations", ad)
+	rec := ts.do("POST", start, buyer, MessageInput{Body: "Is it still available?"})
+	expectStatus(t, rec, http.StatusCreated)
+	var conversation models.Conversation
+	decode(t, rec, &conversation)
+	expectStatus(t, ts.do("POST", start, buyer, MessageInput{Body: "Hello?"}), http.StatusCreated)
+
+	got := auditLog(t, ts, repository.AuditFilter{ActorID: buyerID})
+	want := []string{
+		fmt.Sprintf("conversation %d create", conversation.ID),
+		"message 1 create",
+		"message 2 create",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("buyer's audit log %q, want %q", got, want)
+	}
+	page, err := ts.repos.Audit.List(context.Background(), repository.AuditFilter{Entity: models.EntityMessage, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range page.Entries {
+		if _, ok := entry.Changes["body"]; ok {
+			t.Errorf("message %d recorded with its body: %v", entry.EntityID, entry.Changes)
+		}
+	}
+
+	read := fmt.Sprintf("/conversations/%d/read", conversation.ID)
+	expectStatus(t, ts.do("POST", read, seller, nil), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", read, seller, nil), http.StatusNoContent)
+
+	got = auditLog(t, ts, repository.AuditFilter{ActorID: sellerID, Entity: models.EntityConversation})
+	want = []string{fmt.Sprintf("conversation %d update", conversation.ID)}
+	if !slices.Equal(got, want) {
+		t.Errorf("seller's audit log %q, want %q", got, want)
+	}
+	page, err = ts.repos.Audit.List(context.Background(), repository.AuditFilter{ActorID: sellerID, Entity: models.EntityConversation, Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := page.Entries[0].Changes
+	for field, want := range map[string]float64{"messages_read": 2, "first_message_id": 1, "last_message_id": 2} {
+		if got := changes[field].After; got != want {
+			t.Errorf("reading recorded %s = %v, want %v", field, got, want)
+		}
+	}
+}
+
+func TestAuditRecordsNotificationsRead(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	buyerID, buyer := ts.user("buyer", models.RoleUser)
+	_, seller := ts.user("seller", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+
+	search := models.SavedSearch{UserID: buyerID, Name: "Tools", SubcategoryID: &tools}
+	if err := ts.repos.SavedSearches.Create(ctx, &search); err != nil {
+		t.Fatal(err)
+	}
+	notification := models.Notification{
+		UserID:        buyerID,
+		AdID:          ts.ad(seller, adInput("Rusty spade", tools, 500)),
+		SavedSearchID: search.ID,
+	}
+	if err := ts.repos.Notifications.Create(ctx, &notification); err != nil {
+		t.Fatal(err)
+	}
+
+	read := fmt.Sprintf("/users/%d/notifications/%d/read", buyerID, notification.ID)
+	expectStatus(t, ts.do("POST", read, buyer, nil), http.StatusNoContent)
+	expectStatus(t, ts.do("POST", read, buyer, nil), http.StatusNoContent)
+
+	got := auditLog(t, ts, repository.AuditFilter{Entity: models.EntityNotification})
+	if want := []string{fmt.Sprintf("notification %d update", notification.ID)}; !slices.Equal(got, want) {
+		t.Errorf("audit log %q, want %q", got, want)
+	}
+}
+
+func TestAuditRecordsCascadedDeletes(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	adminID, admin := ts.user("admin", models.RoleAdmin)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+	subcategory, err := ts.repos.Subcategories.Get(ctx, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shed := models.Category{Name: "Shed", ParentID: &subcategory.CategoryID}
+	if err := ts.repos.Categories.Create(ctx, &shed); err != nil {
+		t.Fatal(err)
+	}
+	spade := ts.ad(seller, adInput("Rusty spade", tools, 500))
+	rake := ts.ad(seller, adInput("Old rake", tools, 100))
+
+	path := fmt.Sprintf("/categories/%d?cascade=true", subcategory.CategoryID)
+	expectStatus(t, ts.do("DELETE", path, admin, nil), http.StatusOK)
+
+	got := auditLog(t, ts, repository.AuditFilter{ActorID: adminID, Action: models.AuditDelete})
+	want := []string{
+		fmt.Sprintf("category %d delete", subcategory.CategoryID),
+		fmt.Sprintf("category %d delete", shed.ID),
+		fmt.Sprintf("subcategory %d delete", tools),
+		fmt.Sprintf("ad %d delete", spade),
+		fmt.Sprintf("ad %d delete", rake),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("audit log %q, want %q", got, want)
+	}
+
+	seeds := ts.subcategory("Plants", "Seeds")
+	tomatoes := ts.ad(seller, adInput("Tomato seeds", seeds, 150))
+	expectStatus(t, ts.do("DELETE", fmt.Sprintf("/users/%d", sellerID), seller, nil), http.StatusNoContent)
+
+	got = auditLog(t, ts, repository.AuditFilter{ActorID: sellerID, Action: models.AuditDelete})
+	want = []string{fmt.Sprintf("user %d delete", sellerID), fmt.Sprintf("ad %d delete", tomatoes)}
+	if !slices.Equal(got, want) {
+		t.Errorf("audit log %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	adminOnly  = []string{models.RoleAdmin}
+	moderators = []string{models.RoleModerator, models.RoleAdmin}
+)
+
+// AuthConfig holds the token settings read from the environment:
+// JWT_SECRET (required), ACCESS_TOKEN_TTL and REFRESH_TOKEN_TTL
+// (Go durations such as "15m" or "720h").
+type AuthConfig struct {
+	SigningKey      []byte
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+type TokenClaims struct {
+	UserId uint   `json:"-"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+type contextKey int
+
+const (
+	claimsKey contextKey = iota
+	requestIDKey
+)
+
+func LoadAuthConfig() (AuthConfig, error) {
+	config := AuthConfig{
+		SigningKey:      []byte(os.Getenv("JWT_SECRET")),
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+	}
+
+	if len(config.SigningKey) == 0 {
+		return config, errors.New("JWT_SECRET is not set")
+	}
+
+	if value := os.Getenv("ACCESS_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid ACCESS_TOKEN_TTL: %w", err)
+		}
+		config.AccessTokenTTL = ttl
+	}
+
+	if value := os.Getenv("REFRESH_TOKEN_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid REFRESH_TOKEN_TTL: %w", err)
+		}
+		config.RefreshTokenTTL = ttl
+	}
+
+	return config, nil
+}
+
+// GenerateToken signs an access token for the user.
+func (c AuthConfig) GenerateToken(user models.User) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		Role: user.Role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(c.AccessTokenTTL)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(c.SigningKey)
+}
+
+// ParseToken validates an access token and returns its claims.
+func (c AuthConfig) ParseToken(tokenString string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return c.SigningKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil || userID == 0 {
+		return nil, errors.New("token has no valid subject")
+	}
+	claims.UserId = uint(userID)
+
+	return claims, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// queryToken lets clients that cannot set headers, such as a browser
+// EventSource, pass the access token in the access_token query parameter.
+func queryToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next(w, r)
+	}
+}
+
+// RequireAuth rejects requests without a valid bearer token and stores
+// the authenticated user ID in the request context.
+func (s *Server) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := bearerToken(r)
+		if !ok {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Missing bearer token")
+			return
+		}
+
+		claims, err := s.auth.ParseToken(tokenString)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsKey, claims)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// OptionalAuth is RequireAuth for endpoints that also serve anonymous
+// callers: requests without a valid bearer token, including those with a
+// stale one, pass through unauthenticated.
+func (s *Server) OptionalAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString, ok := bearerToken(r)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		claims, err := s.auth.ParseToken(tokenString)
+		if err != nil {
+			next(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsKey, claims)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// RequireRole is RequireAuth that additionally rejects users whose
+// token does not carry one of the given roles.
+func (s *Server) RequireRole(roles []string, next http.HandlerFunc) http.HandlerFunc {
+	return s.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := RoleFromContext(r.Context())
+		if !slices.Contains(roles, role) {
+			utils.RespondWithError(w, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// isModerator tells whether the request is authenticated as a moderator
+// or an admin.
+func isModerator(r *http.Request) bool {
+	role, _ := RoleFromContext(r.Context())
+	return slices.Contains(moderators, role)
+}
+
+// UserIDFromContext returns the ID of the user authenticated by RequireAuth.
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	claims, ok := ctx.Value(claimsKey).(*TokenClaims)
+	if !ok {
+		return 0, false
+	}
+	return claims.UserId, true
+}
+
+// RoleFromContext returns the role of the user authenticated by RequireAuth.
+func RoleFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(claimsKey).(*TokenClaims)
+	if !ok {
+		return "", false
+	}
+	return claims.Role, true
+}
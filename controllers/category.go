@@ -1,18 +1,29 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/mux"
+	"github.com/sciphilib/go-dacha/events"
 	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
 	"github.com/sciphilib/go-dacha/utils"
 )
 
+// CategoryInput names a category and its parent. A category without a
+// parent sits at the root of the tree. Name is in models.DefaultLocale and
+// Translations holds the name in other locales.
 type CategoryInput struct {
-	Name string `json:"name" validate:"required"`
+	Name         string              `json:"name" validate:"required"`
+	ParentID     *uint               `json:"parent_id"`
+	Translations models.Translations `json:"translations"`
 }
 
 // GetAllCategories godoc
@@ -21,19 +32,107 @@ type CategoryInput struct {
 // @Tags categories
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {array} models.Category "List of categories"
 // @Router /categories [get]
-func GetAllCategories(w http.ResponseWriter, r *http.Request) {
-	var categories []models.Category
-	models.DB.Find(&categories)
+func (s *Server) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.repos.Categories.List(r.Context())
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	l, err := s.localize(r)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	l.renameCategories(categories)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(categories); err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Encoding error")
 		return
 	}
+}
+
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Retrieves all categories nested under their parents, each with its subcategories
+// @Tags categories
+// @Produce json
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {array} models.CategoryNode "The root categories"
+// @Failure 500 {object} string "Internal Server Error"
+// @Router /categories/tree [get]
+func (s *Server) GetCategoryTree(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.repos.Categories.List(r.Context())
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	subcategories, err := s.repos.Subcategories.List(r.Context())
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	l, err := s.localize(r)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	l.renameCategories(categories)
+	l.renameSubcategories(subcategories)
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(categoryTree(categories, subcategories))
+}
+
+// categoryTree nests the categories under their parents and returns the
+// roots. Children keep the order of the input.
+func categoryTree(categories []models.Category, subcategories []models.Subcategory) []models.CategoryNode {
+	children := make(map[uint][]models.Category)
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		}
+	}
+
+	leaves := make(map[uint][]models.SubcategoryNode)
+	for _, subcategory := range subcategories {
+		leaves[subcategory.CategoryID] = append(leaves[subcategory.CategoryID],
+			models.SubcategoryNode{ID: subcategory.ID, Name: subcategory.Name})
+	}
+
+	var build func(categories []models.Category) []models.CategoryNode
+	build = func(categories []models.Category) []models.CategoryNode {
+		nodes := make([]models.CategoryNode, len(categories))
+		for i, category := range categories {
+			nodes[i] = models.CategoryNode{
+				ID:            category.ID,
+				Name:          category.Name,
+				Subcategories: leaves[category.ID],
+				Children:      build(children[category.ID]),
+			}
+			if nodes[i].Subcategories == nil {
+				nodes[i].Subcategories = []models.SubcategoryNode{}
+			}
+		}
+		return nodes
+	}
+
+	return build(roots)
 }
 
 // GetCategory godoc
@@ -43,39 +142,113 @@ func GetAllCategories(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {object} models.Category "Category found"
 // @Failure 404 {object} string "Category not found"
 // @Router /categories/{id} [get]
-func GetCategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var category models.Category
+func (s *Server) GetCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.findCategory(w, r)
+	if !ok {
+		return
+	}
 
-	if err := models.DB.Where("id = ?", id).First(&category).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+	l, err := s.localize(r)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
+	category.Name = l.category(category.ID, category.Name)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
 }
 
+// GetCategoryDescendants godoc
+// @Summary Get the descendants of a category
+// @Description Retrieves every category below the given one, parents before their children
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {array} models.Category "The descendants"
+// @Failure 404 {object} string "Category not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Router /categories/{id}/descendants [get]
+func (s *Server) GetCategoryDescendants(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.findCategory(w, r)
+	if !ok {
+		return
+	}
+
+	descendants, err := s.repos.Categories.Descendants(r.Context(), category.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if descendants == nil {
+		descendants = []models.Category{}
+	}
+
+	l, err := s.localize(r)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	l.renameCategories(descendants)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(descendants)
+}
+
+// findCategory loads the category named by the id route variable and
+// responds with an error if it cannot.
+func (s *Server) findCategory(w http.ResponseWriter, r *http.Request) (models.Category, bool) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+		return models.Category{}, false
+	}
+
+	category, err := s.repos.Categories.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return models.Category{}, false
+	}
+
+	return category, true
+}
+
 // CreateCategory godoc
 // @Summary Create a new category
-// @Description Creates a new category with the provided name
+// @Description Creates a new category with the provided name, under parent_id or at the root. Names are unique across the whole tree. The name is in ru; translations holds it in other locales, keyed by lower case language tag.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Param category body CategoryInput true "Category data"
 // @Success 200 {object} models.Category "Category created"
-// @Failure 400 {object} string "Validation Error"
+// @Failure 400 {object} string "Validation Error or invalid translations"
+// @Failure 400 {object} string "Parent category not found"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 409 {object} string "Category already exists"
+// @Security BearerAuth
 // @Router /categories [post]
-func CreateCategory(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	var input CategoryInput
 
 	body, _ := ioutil.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &input)
 
-	validate = validator.New()
+	validate := validator.New()
 	err := validate.Struct(input)
 
 	if err != nil {
@@ -83,11 +256,29 @@ func CreateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := input.Translations.Check(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid translations: "+err.Error())
+		return
+	}
+
 	category := &models.Category{
-		Name: input.Name,
+		Name:         input.Name,
+		ParentID:     input.ParentID,
+		Translations: input.Translations,
 	}
 
-	models.DB.Create(category)
+	if err := s.repos.Categories.Create(r.Context(), category); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			utils.RespondWithError(w, http.StatusConflict, "Category already exists")
+		} else if errors.Is(err, repository.ErrForeignKey) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Parent category not found")
+		} else {
+			log.Printf("Error creating category: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityCategory, category.ID, models.AuditCreate, nil, category)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(category)
@@ -95,22 +286,25 @@ func CreateCategory(w http.ResponseWriter, r *http.Request) {
 
 // UpdateCategory godoc
 // @Summary Update a category
-// @Description Updates the name of an existing category by ID
+// @Description Updates the name, translations and parent of an existing category by ID. A new parent moves the category with everything below it; leaving parent_id out moves it to the root. The translations replace the stored ones.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
 // @Param category body CategoryInput true "Updated category data"
 // @Success 200 {object} models.Category "Category updated"
-// @Failure 400 {object} string "Validation Error"
+// @Failure 400 {object} string "Validation Error or invalid translations"
+// @Failure 400 {object} string "Parent category not found or cannot move a category under itself"
 // @Failure 404 {object} string "Category not found"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 409 {object} string "Category already exists"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
 // @Router /categories/{id} [put]
-func UpdateCategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var category models.Category
-
-	if err := models.DB.Where("id = ?", id).First(&category).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+func (s *Server) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.findCategory(w, r)
+	if !ok {
 		return
 	}
 
@@ -119,7 +313,7 @@ func UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	body, _ := ioutil.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &input)
 
-	validate = validator.New()
+	validate := validator.New()
 	err := validate.Struct(input)
 
 	if err != nil {
@@ -127,12 +321,48 @@ func UpdateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := input.Translations.Check(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid translations: "+err.Error())
+		return
+	}
+
+	if input.ParentID != nil {
+		parent, err := s.repos.Categories.Get(r.Context(), *input.ParentID)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				utils.RespondWithError(w, http.StatusBadRequest, "Parent category not found")
+			} else {
+				log.Printf("Request error: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+		if category.Contains(parent) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Cannot move a category under itself")
+			return
+		}
+	}
+
+	before := category
 	category.Name = input.Name
+	category.ParentID = input.ParentID
+	category.Translations = input.Translations
 
-	if err := models.DB.Save(&category).Error; err != nil {
-		utils.RespondWithError(w, http.StatusForbidden, "Failed to update category")
+	if err := s.repos.Categories.Update(r.Context(), &category); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "Category already exists")
+		case errors.Is(err, repository.ErrForeignKey):
+			utils.RespondWithError(w, http.StatusBadRequest, "Parent category not found")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+		default:
+			log.Printf("Error updating category: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
 		return
 	}
+	s.audit(r, models.EntityCategory, category.ID, models.AuditUpdate, before, category)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -141,25 +371,163 @@ func UpdateCategory(w http.ResponseWriter, r *http.Request) {
 
 // DeleteCategory godoc
 // @Summary Delete a category
-// @Description Deletes an existing category by ID
+// @Description Soft-deletes an existing category by ID. A category that has child categories or subcategories is only deleted with reassign_to, which moves them to another category outside its subtree, or with cascade=true, which deletes everything below it in the same transaction. Ads never sit under a deleted category.
 // @Tags categories
 // @Accept json
 // @Produce json
 // @Param id path int true "Category ID"
+// @Param reassign_to query int false "ID of the category to move the child categories and subcategories to"
+// @Param cascade query bool false "Delete the descendants, subcategories and ads too"
 // @Success 200 "Category successfully deleted"
+// @Failure 400 {object} string "Invalid query parameters"
 // @Failure 404 {object} string "Category not found"
+// @Failure 409 {object} models.DeleteConflict "Category is not empty"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
 // @Router /categories/{id} [delete]
-func DeleteCategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var category models.Category
+func (s *Server) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	category, ok := s.findCategory(w, r)
+	if !ok {
+		return
+	}
 
-	if err := models.DB.Where("id = ?", id).First(&category).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Category not found")
+	opts, err := parseDeleteOptions(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
 		return
 	}
+	if opts.ReassignTo != 0 {
+		target, err := s.repos.Categories.Get(r.Context(), opts.ReassignTo)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				utils.RespondWithError(w, http.StatusBadRequest, "Category to reassign to not found")
+			} else {
+				log.Printf("Request error: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+		if category.Contains(target) {
+			utils.RespondWithError(w, http.StatusBadRequest, "Cannot reassign to the category itself or a category below it")
+			return
+		}
+	}
 
-	models.DB.Delete(&category)
+	deleted, err := s.repos.Categories.Delete(r.Context(), category.ID, opts)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrForeignKey):
+			s.respondWithDependents(w, r, "Category is not empty", s.repos.Categories.Dependents, category.ID)
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "The category to reassign to has a child category or subcategory with the same name")
+		default:
+			log.Printf("Error deleting category: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityCategory, category.ID, models.AuditDelete, category, nil)
+	s.auditDeleted(r, deleted)
+	for _, ad := range deleted.Ads {
+		s.publishAdEvent(r, events.AdDeleted, ad.ID)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+// parseDeleteOptions reads the reassign_to and cascade parameters of
+// category and subcategory deletes, which exclude each other.
+func parseDeleteOptions(query url.Values) (repository.DeleteOptions, error) {
+	var opts repository.DeleteOptions
+
+	if value := query.Get("reassign_to"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 0)
+		if err != nil || id == 0 {
+			return opts, errors.New("invalid reassign_to")
+		}
+		opts.ReassignTo = uint(id)
+	}
+	if value := query.Get("cascade"); value != "" {
+		cascade, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, err
+		}
+		opts.Cascade = cascade
+	}
+	if opts.ReassignTo != 0 && opts.Cascade {
+		return opts, errors.New("reassign_to and cascade exclude each other")
+	}
+
+	return opts, nil
+}
+
+// respondWithDependents rejects a delete with 409 and the counts of the
+// records that still belong to the node.
+func (s *Server) respondWithDependents(w http.ResponseWriter, r *http.Request, message string,
+	count func(ctx context.Context, id uint) (repository.Dependents, error), id uint) {
+	dependents, err := count(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusConflict)
+	json.NewEncoder(w).Encode(models.DeleteConflict{
+		Error:         message,
+		Categories:    dependents.Categories,
+		Subcategories: dependents.Subcategories,
+		Ads:           dependents.Ads,
+	})
+}
+
+// RestoreCategory godoc
+// @Summary Restore a deleted category
+// @Description Brings back a deleted category with everything below it that was deleted together with it. Its parent must be restored first.
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 204 "Category restored"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Deleted category not found"
+// @Failure 409 {object} string "The parent of the category is deleted, or another category has taken the name"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /categories/{id}/restore [post]
+func (s *Server) RestoreCategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Deleted category not found")
+		return
+	}
+
+	if err := s.repos.Categories.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Deleted category not found")
+		case errors.Is(err, repository.ErrForeignKey):
+			utils.RespondWithError(w, http.StatusConflict, "The parent of the category is deleted")
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "Another category has taken the name")
+		default:
+			log.Printf("Error restoring category: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	category, err := s.repos.Categories.Get(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityCategory, id, models.AuditRestore, nil, category)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestUpdateCategoryErrors(t *testing.T) {
+	ts := newTestServer(t)
+	_, admin := ts.user("root", models.RoleAdmin)
+
+	create := func(name string, parentID *uint) uint {
+		rec := ts.do("POST", "/categories", admin, CategoryInput{Name: name, ParentID: parentID})
+		expectStatus(t, rec, http.StatusOK)
+		var category models.Category
+		decode(t, rec, &category)
+		return category.ID
+	}
+	garden := create("Garden", nil)
+	tools := create("Tools", &garden)
+	plants := create("Plants", nil)
+	create("Tools", &plants)
+
+	missing := uint(999)
+	tests := []struct {
+		name  string
+		id    uint
+		input CategoryInput
+		want  int
+	}{
+		{"duplicate name at the root", tools, CategoryInput{Name: "Plants"}, http.StatusConflict},
+		{"duplicate name under the new parent", tools, CategoryInput{Name: "Tools", ParentID: &plants}, http.StatusConflict},
+		{"missing parent", tools, CategoryInput{Name: "Tools", ParentID: &missing}, http.StatusBadRequest},
+		{"under itself", garden, CategoryInput{Name: "Garden", ParentID: &garden}, http.StatusBadRequest},
+		{"under its child", garden, CategoryInput{Name: "Garden", ParentID: &tools}, http.StatusBadRequest},
+		{"missing category", missing, CategoryInput{Name: "Garden"}, http.StatusNotFound},
+		{"name taken under another parent", tools, CategoryInput{Name: "Plants", ParentID: &garden}, http.StatusOK},
+		{"rename and move to the root", tools, CategoryInput{Name: "Garden tools"}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			expectStatus(t, ts.do("PUT", fmt.Sprintf("/categories/%d", test.id), admin, test.input), test.want)
+		})
+	}
+}
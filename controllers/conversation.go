@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+type MessageInput struct {
+	Body string `json:"body" validate:"required,max=4000"`
+}
+
+// StartConversation godoc
+// @Summary Contact the owner of an ad
+// @Description Starts a conversation about the ad with its owner and posts the first message. Starting a conversation the caller already has for the ad posts the message to it.
+// @Tags conversations
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param message body MessageInput true "First message"
+// @Success 201 {object} models.Conversation "The conversation"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot start a conversation about your own ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/conversations [post]
+func (s *Server) StartConversation(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	if ad.User_id == userID {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot start a conversation about your own ad")
+		return
+	}
+
+	input, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	conversation, started, err := s.repos.Conversations.Start(r.Context(), ad.ID, userID, ad.User_id)
+	if err != nil {
+		if errors.Is(err, repository.ErrForeignKey) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		} else {
+			log.Printf("Error starting conversation: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	if started {
+		s.audit(r, models.EntityConversation, conversation.ID, models.AuditCreate, nil, conversation)
+	}
+
+	message := models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           input.Body,
+	}
+	if err := s.repos.Conversations.AddMessage(r.Context(), &message); err != nil {
+		log.Printf("Error posting message: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityMessage, message.ID, models.AuditCreate, nil, messageRecord(message))
+	conversation.LastMessageAt = &message.CreatedAt
+	s.publishMessage(conversation, message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(conversation)
+}
+
+// GetConversations godoc
+// @Summary Get the caller's conversations
+// @Description Lists the conversations the caller takes part in as buyer or seller, most recently active first, with the number of unread messages in each
+// @Tags conversations
+// @Produce json
+// @Success 200 {array} models.ConversationSummary "List of conversations"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /conversations [get]
+func (s *Server) GetConversations(w http.ResponseWriter, r *http.Request) {
+	userID, _ := UserIDFromContext(r.Context())
+
+	conversations, err := s.repos.Conversations.ListForUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if conversations == nil {
+		conversations = []models.ConversationSummary{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(conversations)
+}
+
+// GetMessages godoc
+// @Summary Get the messages of a conversation
+// @Description Retrieves messages oldest first. Pass the ID of the last message received as after_id to fetch the next ones.
+// @Tags conversations
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param after_id query int false "Return messages with ID greater than this"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {array} models.Message "List of messages"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "Conversation not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /conversations/{id}/messages [get]
+func (s *Server) GetMessages(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := s.findConversation(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	limit, _, err := parsePage(query)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	var afterID uint64
+	if value := query.Get("after_id"); value != "" {
+		if afterID, err = strconv.ParseUint(value, 10, 0); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+			return
+		}
+	}
+
+	messages, err := s.repos.Conversations.Messages(r.Context(), conversation.ID, uint(afterID), limit)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if messages == nil {
+		messages = []models.Message{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(messages)
+}
+
+// PostMessage godoc
+// @Summary Post a message
+// @Description Posts a message to a conversation the caller takes part in
+// @Tags conversations
+// @Accept json
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Param message body MessageInput true "Message"
+// @Success 201 {object} models.Message "The posted message"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "Conversation not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /conversations/{id}/messages [post]
+func (s *Server) PostMessage(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := s.findConversation(w, r)
+	if !ok {
+		return
+	}
+
+	input, ok := decodeMessage(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	message := models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       userID,
+		Body:           input.Body,
+	}
+	if err := s.repos.Conversations.AddMessage(r.Context(), &message); err != nil {
+		log.Printf("Error posting message: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityMessage, message.ID, models.AuditCreate, nil, messageRecord(message))
+	s.publishMessage(conversation, message)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// MarkConversationRead godoc
+// @Summary Mark a conversation as read
+// @Description Marks every message the other participant has sent in the conversation as read
+// @Tags conversations
+// @Produce json
+// @Param id path int true "Conversation ID"
+// @Success 204 "Messages marked as read"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 404 {object} string "Conversation not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /conversations/{id}/read [post]
+func (s *Server) MarkConversationRead(w http.ResponseWriter, r *http.Request) {
+	conversation, ok := s.findConversation(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	read, err := s.repos.Conversations.MarkRead(r.Context(), conversation.ID, userID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if len(read) > 0 {
+		s.audit(r, models.EntityConversation, conversation.ID, models.AuditUpdate, nil, readRecord(read))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findConversation loads the conversation named by the id path variable.
+// Conversations the caller does not take part in are reported as not found
+// so that their existence is not revealed.
+func (s *Server) findConversation(w http.ResponseWriter, r *http.Request) (models.Conversation, bool) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Conversation not found")
+		return models.Conversation{}, false
+	}
+
+	conversation, err := s.repos.Conversations.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Conversation not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return models.Conversation{}, false
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	if !conversation.HasParticipant(userID) {
+		utils.RespondWithError(w, http.StatusNotFound, "Conversation not found")
+		return models.Conversation{}, false
+	}
+
+	return conversation, true
+}
+
+// publishMessage notifies the participant who did not send the message.
+func (s *Server) publishMessage(conversation models.Conversation, message models.Message) {
+	recipient := conversation.SellerID
+	if message.SenderID == recipient {
+		recipient = conversation.BuyerID
+	}
+	s.broker.Publish(events.Event{Type: events.MessageCreated, Data: message}, recipient)
+}
+
+func decodeMessage(w http.ResponseWriter, r *http.Request) (MessageInput, bool) {
+	var input MessageInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return input, false
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return input, false
+	}
+
+	return input, true
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+// heartbeatInterval is how often an idle event stream sends a comment so
+// that proxies do not close it.
+const heartbeatInterval = 30 * time.Second
+
+// tokenExpiredEvent is the last event of a stream, sent when the access
+// token it was opened with expires. The client reconnects with a fresh
+// token.
+const tokenExpiredEvent = "token.expired"
+
+// StreamEvents godoc
+// @Summary Subscribe to notifications
+// @Description Streams the caller's notifications as Server-Sent Events until the client disconnects. Each event is named after its type (message.created, ad.updated, ad.deleted, ad.matched) and carries the JSON payload in its data field. Browsers that cannot set headers on an EventSource may pass the access token in the access_token query parameter instead. The stream ends with a token.expired event when the access token expires; reconnect with a fresh one.
+// @Tags events
+// @Produce text/event-stream
+// @Param access_token query string false "Access token, if not sent in the Authorization header"
+// @Success 200 {object} events.Event "Stream of events"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 500 {object} string "Streaming unsupported"
+// @Security BearerAuth
+// @Router /events [get]
+func (s *Server) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.RespondWithError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	claims, _ := r.Context().Value(claimsKey).(*TokenClaims)
+	stream, cancel := s.broker.Subscribe(claims.UserId)
+	defer cancel()
+
+	// ParseToken requires exp, so the stream never outlives the token.
+	expired := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+	defer expired.Stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired.C:
+			fmt.Fprintf(w, "event: %s\ndata: {}\n\n", tokenExpiredEvent)
+			flusher.Flush()
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event := <-stream:
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				log.Printf("Error encoding event: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// publishAdEvent notifies the users who bookmarked the ad.
+func (s *Server) publishAdEvent(r *http.Request, eventType string, adID uint) {
+	users, err := s.repos.Favorites.Users(r.Context(), adID)
+	if err != nil {
+		log.Printf("Error loading users to notify: %v", err)
+		return
+	}
+	s.publishAdEventTo(eventType, adID, users)
+}
+
+func (s *Server) publishAdEventTo(eventType string, adID uint, users []uint) {
+	s.broker.Publish(events.Event{
+		Type: eventType,
+		Data: map[string]interface{}{"ad_id": adID},
+	}, users...)
+}
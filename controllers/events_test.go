@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestStreamEndsWhenTokenExpires(t *testing.T) {
+	ts := newTestServer(t)
+	userID, _ := ts.user("alice", models.RoleUser)
+
+	short := ts.auth
+	short.AccessTokenTTL = time.Second
+	token, err := short.GenerateToken(models.User{ID: userID, Role: models.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(ts.handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?access_token=" + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	ts.broker.Publish(events.Event{Type: events.AdUpdated, Data: map[string]uint{"ad_id": 1}}, userID)
+
+	done := make(chan []string)
+	go func() {
+		var lines []string
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+				lines = append(lines, strings.TrimPrefix(line, "event: "))
+			}
+		}
+		done <- lines
+	}()
+
+	select {
+	case got := <-done:
+		if want := []string{events.AdUpdated, tokenExpiredEvent}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("events %q, want %q", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after the token expired")
+	}
+}
+
+func TestOptionalAuthTreatsInvalidTokenAsAnonymous(t *testing.T) {
+	ts := newTestServer(t)
+	userID, seller := ts.user("seller", models.RoleUser)
+	draft := adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500)
+	draft["status"] = models.StatusDraft
+	path := fmt.Sprintf("/ads/%d", ts.ad(seller, draft))
+
+	expired := ts.auth
+	expired.AccessTokenTTL = -time.Minute
+	stale, err := expired.GenerateToken(models.User{ID: userID, Role: models.RoleUser})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{stale, "garbage"} {
+		expectStatus(t, ts.do("GET", "/ads", token, nil), http.StatusOK)
+		// The stale token does not let the owner see their drafts.
+		expectStatus(t, ts.do("GET", path, token, nil), http.StatusNotFound)
+	}
+	expectStatus(t, ts.do("GET", path, seller, nil), http.StatusOK)
+	expectStatus(t, ts.do("POST", "/ads", stale, draft), http.StatusUnauthorized)
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+type FavoriteInput struct {
+	AdID uint `json:"ad_id" validate:"required"`
+}
+
+// GetFavorites godoc
+// @Summary Get a user's favorite ads
+// @Description Retrieves a page of the ads the user has bookmarked, most recently added first. Users can only see their own favorites. Drafts, pending, rejected and hidden ads of other users are left out.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of ads to skip"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {object} models.AdPage "A page of advertisement objects"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot access another user's favorites"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/favorites [get]
+func (s *Server) GetFavorites(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot access another user's favorites")
+		return
+	}
+
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	page, err := s.repos.Favorites.List(r.Context(), id, limit, offset)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	formatted, err := s.formatAds(r, page.Ads)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithAdPage(w, formatted, models.Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   page.Total,
+		HasMore: page.HasMore,
+	})
+}
+
+// AddFavorite godoc
+// @Summary Add an ad to favorites
+// @Description Bookmarks an ad for the user. Adding an ad that is already a favorite has no effect. Drafts, pending, rejected and hidden ads can only be bookmarked by their owner.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param favorite body FavoriteInput true "Ad to bookmark"
+// @Success 204 "Ad added to favorites"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's favorites"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/favorites [post]
+func (s *Server) AddFavorite(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot change another user's favorites")
+		return
+	}
+
+	var input FavoriteInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	ad, err := s.repos.Ads.Get(r.Context(), input.AdID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	if !canSee(r, ad) {
+		utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		return
+	}
+
+	added, err := s.repos.Favorites.Add(r.Context(), id, input.AdID)
+	if err != nil {
+		if errors.Is(err, repository.ErrForeignKey) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		} else {
+			log.Printf("Error adding favorite: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	if added {
+		s.audit(r, models.EntityFavorite, input.AdID, models.AuditCreate, nil, favoriteRecord(id, input.AdID))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFavorite godoc
+// @Summary Remove an ad from favorites
+// @Description Removes a bookmarked ad from the user's favorites
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param ad_id path int true "Ad ID"
+// @Success 204 "Ad removed from favorites"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's favorites"
+// @Failure 404 {object} string "Ad is not a favorite"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/favorites/{ad_id} [delete]
+func (s *Server) RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot change another user's favorites")
+		return
+	}
+
+	adID, ok := pathID(r, "ad_id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Ad is not a favorite")
+		return
+	}
+
+	if err := s.repos.Favorites.Remove(r.Context(), id, adID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad is not a favorite")
+		} else {
+			log.Printf("Error removing favorite: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityFavorite, adID, models.AuditDelete, favoriteRecord(id, adID), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
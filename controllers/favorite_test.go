@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestFavoritesHidePrivateAds(t *testing.T) {
+	ts := newTestServer(t)
+	buyerID, buyer := ts.user("buyer", models.RoleUser)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+
+	draftInput := adInput("Draft spade", tools, 500)
+	draftInput["status"] = models.StatusDraft
+	draft := ts.ad(seller, draftInput)
+	active := ts.ad(seller, adInput("Garden hose", tools, 2500))
+
+	buyerFavorites := fmt.Sprintf("/users/%d/favorites", buyerID)
+	expectStatus(t, ts.do("POST", buyerFavorites, buyer, FavoriteInput{AdID: draft}), http.StatusNotFound)
+	expectStatus(t, ts.do("POST", buyerFavorites, buyer, FavoriteInput{AdID: active}), http.StatusNoContent)
+	if got := titles(t, ts, buyerFavorites, buyer); !slices.Equal(got, []string{"Garden hose"}) {
+		t.Fatalf("favorites %q", got)
+	}
+
+	// Taking the ad back to draft hides it from the buyer's favorites.
+	status := fmt.Sprintf("/ads/%d/status", active)
+	expectStatus(t, ts.do("PUT", status, seller, AdStatusInput{Status: models.StatusDraft}), http.StatusOK)
+	if got := titles(t, ts, buyerFavorites, buyer); len(got) != 0 {
+		t.Fatalf("favorites list drafts of other users: %q", got)
+	}
+
+	sellerFavorites := fmt.Sprintf("/users/%d/favorites", sellerID)
+	expectStatus(t, ts.do("POST", sellerFavorites, seller, FavoriteInput{AdID: draft}), http.StatusNoContent)
+	if got := titles(t, ts, sellerFavorites, seller); !slices.Equal(got, []string{"Draft spade"}) {
+		t.Fatalf("owner's favorites %q", got)
+	}
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+// acceptedLocales lists the locales of an Accept-Language header, most
+// preferred first and in lower case. A tag with a region is followed by
+// its bare language. The list ends at the first tag in
+// models.DefaultLocale since every name exists in it.
+func acceptedLocales(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	var locales []string
+	for _, tag := range tags {
+		language, _, _ := strings.Cut(tag.locale, "-")
+		if language == models.DefaultLocale {
+			break
+		}
+		for _, locale := range []string{tag.locale, language} {
+			if !slices.Contains(locales, locale) {
+				locales = append(locales, locale)
+			}
+		}
+	}
+	return locales
+}
+
+// localizer resolves the names of categories and subcategories in the
+// locales a request accepts.
+type localizer struct {
+	locales       []string
+	categories    map[uint]models.Translations
+	subcategories map[uint]models.Translations
+}
+
+// localize loads the translations the request asks for through its
+// Accept-Language header.
+func (s *Server) localize(r *http.Request) (localizer, error) {
+	l := localizer{locales: acceptedLocales(r.Header.Get("Accept-Language"))}
+	if len(l.locales) == 0 {
+		return l, nil
+	}
+
+	var err error
+	l.categories, err = s.repos.Categories.Translations(r.Context(), l.locales)
+	if err != nil {
+		return l, err
+	}
+	l.subcategories, err = s.repos.Subcategories.Translations(r.Context(), l.locales)
+	return l, err
+}
+
+func (l localizer) category(id uint, name string) string {
+	return l.categories[id].Resolve(name, l.locales)
+}
+
+func (l localizer) subcategory(id uint, name string) string {
+	return l.subcategories[id].Resolve(name, l.locales)
+}
+
+// renameCategories puts the names of the categories in the locale of l.
+func (l localizer) renameCategories(categories []models.Category) {
+	for i := range categories {
+		categories[i].Name = l.category(categories[i].ID, categories[i].Name)
+	}
+}
+
+// renameSubcategories puts the names of the subcategories and their
+// categories in the locale of l.
+func (l localizer) renameSubcategories(subcategories []models.Subcategory) {
+	for i := range subcategories {
+		subcategory := &subcategories[i]
+		subcategory.Name = l.subcategory(subcategory.ID, subcategory.Name)
+		subcategory.Category.Name = l.category(subcategory.Category.ID, subcategory.Category.Name)
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestAcceptedLocales(t *testing.T) {
+	tests := []struct {
+		header string
+		want   []string
+	}{
+		{"", nil},
+		{"en", []string{"en"}},
+		{"EN-us", []string{"en-us", "en"}},
+		{"en-US,en;q=0.9", []string{"en-us", "en"}},
+		{"de;q=0.5, en-GB", []string{"en-gb", "en", "de"}},
+		{"pt-BR, pt-PT", []string{"pt-br", "pt", "pt-pt"}},
+		{"de, en", []string{"de", "en"}},
+		{"de;q=0.8, en;q=0.8", []string{"de", "en"}},
+		{"fr;q=0, en", []string{"en"}},
+		{"*, en;q=0.1", []string{"en"}},
+		{"en;q=abc, de", []string{"de"}},
+		{" , en , ", []string{"en"}},
+		// Every name exists in the default locale, so the list ends there.
+		{"ru, en", nil},
+		{"en, ru-RU, de", []string{"en"}},
+		{"en-US;q=0.8, de;q=0.8, ru;q=0.9", nil},
+		{"rue, en", []string{"rue", "en"}},
+	}
+	for _, test := range tests {
+		if got := acceptedLocales(test.header); !slices.Equal(got, test.want) {
+			t.Errorf("acceptedLocales(%q) = %q, want %q", test.header, got, test.want)
+		}
+	}
+}
+
+func TestCategoryNamesFollowAcceptLanguage(t *testing.T) {
+	ts := newTestServer(t)
+	_, admin := ts.user("root", models.RoleAdmin)
+	expectStatus(t, ts.do("POST", "/categories", admin, CategoryInput{
+		Name:         "Сад",
+		Translations: models.Translations{"en": "Garden", "en-gb": "Garden (UK)"},
+	}), http.StatusOK)
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "Сад"},
+		{"en", "Garden"},
+		{"en-GB, en;q=0.9", "Garden (UK)"},
+		{"en-US", "Garden"},
+		{"de, ru;q=0.9, en;q=0.8", "Сад"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest("GET", "/categories", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Language", test.header)
+		rec := ts.serve(req)
+		expectStatus(t, rec, http.StatusOK)
+
+		var categories []models.Category
+		decode(t, rec, &categories)
+		if len(categories) != 1 || categories[0].Name != test.want {
+			t.Errorf("Accept-Language %q: categories %+v, want %q", test.header, categories, test.want)
+		}
+	}
+}
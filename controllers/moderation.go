@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+// ReportInput says what is wrong with an ad. A report for another reason
+// must explain itself in the comment.
+type ReportInput struct {
+	Reason  string `json:"reason" validate:"required,oneof=scam prohibited spam duplicate wrong_category offensive other"`
+	Comment string `json:"comment" validate:"required_if=Reason other,max=1000"`
+}
+
+// ModerationInput is a moderator's decision on an ad. Rejecting and
+// hiding need a reason.
+type ModerationInput struct {
+	Action string `json:"action" validate:"required,oneof=approve reject hide"`
+	Reason string `json:"reason" validate:"required_unless=Action approve,max=1000"`
+}
+
+// ReportAd godoc
+// @Summary Report an advertisement
+// @Description Flags an ad for moderators, for example as a scam or a prohibited listing. A user can have one open report per ad; it stays open until a moderator acts on the ad.
+// @Tags advertisements
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param report body ReportInput true "Reason for the report"
+// @Success 201 {object} models.Report "The report"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot report your own ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 409 {object} string "You have already reported this ad"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/report [post]
+func (s *Server) ReportAd(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+	if isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot report your own ad")
+		return
+	}
+
+	var input ReportInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	userID, _ := UserIDFromContext(r.Context())
+	report := models.Report{
+		AdID:    ad.ID,
+		UserID:  userID,
+		Reason:  input.Reason,
+		Comment: input.Comment,
+	}
+
+	if err := s.repos.Moderation.Report(r.Context(), &report); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "You have already reported this ad")
+		case errors.Is(err, repository.ErrForeignKey):
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		default:
+			log.Printf("Error reporting ad: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityReport, report.ID, models.AuditCreate, nil, report)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetModerationQueue godoc
+// @Summary Get the moderation queue
+// @Description Retrieves a page of the ads awaiting review, each with its open reports. Reported ads come first, most reported first, followed by the ads nobody has reviewed yet, oldest first.
+// @Tags moderation
+// @Produce json
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of ads to skip"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
+// @Success 200 {object} models.ModerationQueue "A page of ads awaiting review"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /moderation/queue [get]
+func (s *Server) GetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	page, err := s.repos.Moderation.Queue(r.Context(), limit, offset)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	ids := make([]uint, len(page.Ads))
+	for i, ad := range page.Ads {
+		ids[i] = ad.ID
+	}
+	reports, err := s.repos.Moderation.OpenReports(r.Context(), ids)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	formatted, err := s.formatAds(r, page.Ads)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	for i, ad := range page.Ads {
+		formatted[i]["reports"] = reports[ad.ID]
+		if reports[ad.ID] == nil {
+			formatted[i]["reports"] = []models.Report{}
+		}
+	}
+
+	respondWithAdPage(w, formatted, models.Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   page.Total,
+		HasMore: page.HasMore,
+	})
+}
+
+// ModerateAd godoc
+// @Summary Approve, reject or hide an advertisement
+// @Description Records a moderator's decision on an ad and resolves its open reports. Rejecting moves the ad to rejected, which its owner can take back to draft to fix it; hiding moves it to hidden, which only a moderator can undo. Approving brings back a rejected, hidden or pending ad as active, or as expired once its expiry has passed, and leaves any other status alone.
+// @Tags moderation
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param decision body ModerationInput true "Action and reason"
+// @Success 201 {object} models.ModerationAction "The recorded action"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 409 {object} string "The ad status has changed"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/moderation [post]
+func (s *Server) ModerateAd(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	var input ModerationInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	before, err := s.loadAdRecord(r, ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	moderatorID, _ := UserIDFromContext(r.Context())
+	action := models.ModerationAction{
+		AdID:        ad.ID,
+		ModeratorID: moderatorID,
+		Action:      input.Action,
+		Reason:      input.Reason,
+		FromStatus:  ad.Status,
+		ToStatus:    models.ModeratedStatus(input.Action, ad, time.Now()),
+	}
+
+	if err := s.repos.Moderation.Act(r.Context(), &action); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusConflict, "The ad status has changed, try again")
+		} else {
+			log.Printf("Error moderating ad: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.auditAd(r, ad.ID, models.AuditUpdate, before)
+
+	if action.ToStatus != action.FromStatus {
+		s.publishAdEvent(r, events.AdUpdated, ad.ID)
+		if action.ToStatus == models.StatusActive {
+			s.matcher.Wake()
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(action)
+}
+
+// GetModerationActions godoc
+// @Summary Get the moderation history of an advertisement
+// @Description Retrieves the actions moderators took on an ad, newest first
+// @Tags moderation
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Success 200 {array} models.ModerationAction "The actions"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/moderation [get]
+func (s *Server) GetModerationActions(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	actions, err := s.repos.Moderation.Actions(r.Context(), ad.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if actions == nil {
+		actions = []models.ModerationAction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(actions)
+}
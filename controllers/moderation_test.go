@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestApproveRestoresExpiredAds(t *testing.T) {
+	ts := newTestServer(t)
+	sellerID, _ := ts.user("seller", models.RoleUser)
+	_, moderator := ts.user("moderator", models.RoleModerator)
+	tools := ts.subcategory("Garden", "Tools")
+
+	tests := []struct {
+		from      string
+		expiresAt time.Time
+		want      string
+	}{
+		{models.StatusHidden, time.Now().Add(time.Hour), models.StatusActive},
+		{models.StatusHidden, time.Now().Add(-time.Hour), models.StatusExpired},
+		{models.StatusRejected, time.Now().Add(-time.Hour), models.StatusExpired},
+		{models.StatusSold, time.Now().Add(-time.Hour), models.StatusSold},
+		{models.StatusPending, time.Now().Add(time.Hour), models.StatusActive},
+		{models.StatusPending, time.Now().Add(-time.Hour), models.StatusExpired},
+	}
+	for _, test := range tests {
+		ad := models.Advertisement{
+			Title:          "Rusty spade",
+			Subcategory_id: tools,
+			User_id:        sellerID,
+			Status:         test.from,
+			ExpiresAt:      test.expiresAt,
+		}
+		if err := ts.repos.Ads.Create(context.Background(), &ad); err != nil {
+			t.Fatal(err)
+		}
+
+		path := fmt.Sprintf("/ads/%d/moderation", ad.ID)
+		rec := ts.do("POST", path, moderator, ModerationInput{Action: models.ActionApprove})
+		expectStatus(t, rec, http.StatusCreated)
+		var action models.ModerationAction
+		decode(t, rec, &action)
+		if action.ToStatus != test.want {
+			t.Errorf("approving a %s ad expiring at %v moved it to %q, want %q", test.from, test.expiresAt, action.ToStatus, test.want)
+		}
+	}
+}
+
+func TestReportAd(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	_, buyer := ts.user("buyer", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+	draft := adInput("Draft spade", tools, 500)
+	draft["status"] = models.StatusDraft
+	report := ReportInput{Reason: models.ReportSpam}
+
+	active := fmt.Sprintf("/ads/%d/report", ts.ad(seller, adInput("Rusty spade", tools, 500)))
+	expectStatus(t, ts.do("POST", fmt.Sprintf("/ads/%d/report", ts.ad(seller, draft)), buyer, report), http.StatusNotFound)
+	expectStatus(t, ts.do("POST", active, seller, report), http.StatusForbidden)
+	expectStatus(t, ts.do("POST", active, buyer, report), http.StatusCreated)
+	expectStatus(t, ts.do("POST", active, buyer, report), http.StatusConflict)
+}
+
+func TestRejectedAdsNeedApprovalToBeListedAgain(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	_, moderator := ts.user("moderator", models.RoleModerator)
+	id := ts.ad(seller, adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500))
+	path := fmt.Sprintf("/ads/%d", id)
+
+	setStatus := func(status string) *httptest.ResponseRecorder {
+		return ts.do("PUT", path+"/status", seller, AdStatusInput{Status: status})
+	}
+	expectAdStatus := func(rec *httptest.ResponseRecorder, want string) {
+		t.Helper()
+		expectStatus(t, rec, http.StatusOK)
+		var status models.AdStatus
+		decode(t, rec, &status)
+		if status.Status != want {
+			t.Fatalf("status %q, want %q", status.Status, want)
+		}
+	}
+	moderate := func(action string) {
+		t.Helper()
+		input := ModerationInput{Action: action, Reason: "Prohibited item"}
+		expectStatus(t, ts.do("POST", path+"/moderation", moderator, input), http.StatusCreated)
+	}
+	queued := func() bool {
+		t.Helper()
+		page, err := ts.repos.Moderation.Queue(context.Background(), 100, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ad := range page.Ads {
+			if ad.ID == id {
+				return true
+			}
+		}
+		return false
+	}
+
+	moderate(models.ActionReject)
+	expectAdStatus(setStatus(models.StatusDraft), models.StatusDraft)
+	expectAdStatus(setStatus(models.StatusActive), models.StatusPending)
+	expectStatus(t, ts.do("GET", path, "", nil), http.StatusNotFound)
+	if !queued() {
+		t.Error("the pending ad is not queued for review")
+	}
+	expectStatus(t, setStatus(models.StatusActive), http.StatusConflict)
+
+	// Taking it back to draft and publishing again does not skip review.
+	expectAdStatus(setStatus(models.StatusDraft), models.StatusDraft)
+	expectAdStatus(setStatus(models.StatusActive), models.StatusPending)
+
+	moderate(models.ActionApprove)
+	expectStatus(t, ts.do("GET", path, "", nil), http.StatusOK)
+	if queued() {
+		t.Error("the approved ad is still queued for review")
+	}
+
+	expectAdStatus(setStatus(models.StatusDraft), models.StatusDraft)
+	expectAdStatus(setStatus(models.StatusActive), models.StatusActive)
+}
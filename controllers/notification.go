@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+// GetNotifications godoc
+// @Summary Get a user's notifications
+// @Description Retrieves the user's notifications of new ads matching their saved searches, newest first
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Param offset query int false "Number of notifications to skip"
+// @Success 200 {array} models.Notification "List of notifications"
+// @Failure 400 {object} string "Invalid query parameters"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot access another user's notifications"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/notifications [get]
+func (s *Server) GetNotifications(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot access another user's notifications")
+		return
+	}
+
+	limit, offset, err := parsePage(r.URL.Query())
+	if err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+
+	notifications, err := s.repos.Notifications.List(r.Context(), id, limit, offset)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification as read
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param notification_id path int true "Notification ID"
+// @Success 204 "Notification marked as read"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's notifications"
+// @Failure 404 {object} string "Notification not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/notifications/{notification_id}/read [post]
+func (s *Server) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot change another user's notifications")
+		return
+	}
+
+	notificationID, ok := pathID(r, "notification_id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Notification not found")
+		return
+	}
+
+	notification, marked, err := s.repos.Notifications.MarkRead(r.Context(), id, notificationID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Notification not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	if marked {
+		before := notification
+		before.ReadAt = nil
+		s.audit(r, models.EntityNotification, notification.ID, models.AuditUpdate, before, notification)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
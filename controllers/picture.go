@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/gorilla/mux"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/images"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/storage"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+const (
+	maxPicturesPerAd = 10
+	maxPictureSize   = 10 << 20
+	maxUploadSize    = maxPicturesPerAd * maxPictureSize
+)
+
+var pictureTypes = []string{"image/jpeg", "image/png"}
+
+type PicturesOrderInput struct {
+	Pictures []string `json:"pictures" validate:"required"`
+}
+
+// UploadAdPictures godoc
+// @Summary Upload ad pictures
+// @Description Uploads one or more JPEG or PNG images of up to 10 MB each and appends them to the ad's pictures. Each image is rotated upright according to its EXIF orientation and stripped of metadata, and medium and thumbnail variants are generated. An ad holds at most 10 pictures.
+// @Tags advertisements
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param pictures formData file true "Images to upload; repeat the field for several files"
+// @Success 200 {object} models.AdPictures "Pictures of the ad"
+// @Failure 400 {object} string "Invalid upload or too many pictures"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Not the owner of the ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 413 {object} string "File too large"
+// @Failure 415 {object} string "Unsupported image type"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/pictures [post]
+func (s *Server) UploadAdPictures(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxPictureSize); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "File too large")
+		} else {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid multipart form")
+		}
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	headers := r.MultipartForm.File["pictures"]
+	if len(headers) == 0 {
+		utils.RespondWithError(w, http.StatusBadRequest, "No pictures uploaded")
+		return
+	}
+	// The repository enforces the limit when the pictures are appended;
+	// checking early as well saves processing uploads bound to fail.
+	if len(ad.Pictures)+len(headers) > maxPicturesPerAd {
+		utils.RespondWithError(w, http.StatusBadRequest, "Too many pictures")
+		return
+	}
+
+	var keys, urls []string
+	discard := func() {
+		for _, key := range keys {
+			s.deletePictureKey(r, key)
+		}
+	}
+
+	for _, header := range headers {
+		if header.Size > maxPictureSize {
+			discard()
+			utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "File too large")
+			return
+		}
+
+		key, err := s.storePicture(r, ad.ID, header)
+		if err != nil {
+			discard()
+			switch {
+			case errors.Is(err, errUnsupportedType), errors.Is(err, images.ErrUnsupported):
+				utils.RespondWithError(w, http.StatusUnsupportedMediaType, "Unsupported image type")
+			case errors.Is(err, images.ErrTooLarge):
+				utils.RespondWithError(w, http.StatusRequestEntityTooLarge, "Image dimensions too large")
+			default:
+				log.Printf("Error storing picture: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+		keys = append(keys, key)
+		urls = append(urls, s.files.URL(key))
+	}
+
+	pictures, err := s.repos.Ads.AppendPictures(r.Context(), ad.ID, urls, maxPicturesPerAd)
+	if err != nil {
+		discard()
+		switch {
+		case errors.Is(err, repository.ErrLimit):
+			utils.RespondWithError(w, http.StatusBadRequest, "Too many pictures")
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		default:
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityAd, ad.ID, models.AuditUpdate, pictureRecord(ad.Pictures), pictureRecord(pictures))
+	s.publishAdEvent(r, events.AdUpdated, ad.ID)
+
+	s.respondWithPictures(w, pictures)
+}
+
+var errUnsupportedType = errors.New("unsupported image type")
+
+// storePicture checks the content type of an uploaded file, processes
+// it and stores the original and its variants under a random name.
+func (s *Server) storePicture(r *http.Request, adID uint, header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	mtype, err := mimetype.DetectReader(file)
+	if err != nil {
+		return "", err
+	}
+	if !slices.ContainsFunc(pictureTypes, mtype.Is) {
+		return "", errUnsupportedType
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	processed, err := images.Process(file)
+	if err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("ads/%d/%s%s", adID, hex.EncodeToString(name), processed.Extension)
+
+	if err := s.files.Put(r.Context(), key, bytes.NewReader(processed.Original)); err != nil {
+		return "", err
+	}
+	for _, variant := range images.Variants {
+		variantKey := images.VariantKey(key, variant.Name)
+		if err := s.files.Put(r.Context(), variantKey, bytes.NewReader(processed.Variants[variant.Name])); err != nil {
+			s.deletePictureKey(r, key)
+			return "", err
+		}
+	}
+	return key, nil
+}
+
+// DeleteAdPicture godoc
+// @Summary Delete an ad picture
+// @Description Removes a picture from the ad and deletes the stored original and variants. The picture is named by the last element of its original URL.
+// @Tags advertisements
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param name path string true "File name of the picture"
+// @Success 200 {object} models.AdPictures "Remaining pictures of the ad"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Not the owner of the ad"
+// @Failure 404 {object} string "Ad or picture not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/pictures/{name} [delete]
+func (s *Server) DeleteAdPicture(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	i := slices.IndexFunc(ad.Pictures, func(url string) bool { return path.Base(url) == name })
+	if i < 0 {
+		utils.RespondWithError(w, http.StatusNotFound, "Picture not found")
+		return
+	}
+	url := ad.Pictures[i]
+
+	pictures, err := s.repos.Ads.RemovePicture(r.Context(), ad.ID, url)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	s.audit(r, models.EntityAd, ad.ID, models.AuditUpdate, pictureRecord(ad.Pictures), pictureRecord(pictures))
+	s.deletePictureFiles(r, []string{url})
+	s.publishAdEvent(r, events.AdUpdated, ad.ID)
+
+	s.respondWithPictures(w, pictures)
+}
+
+// ReorderAdPictures godoc
+// @Summary Reorder ad pictures
+// @Description Sets the order of the ad's pictures. The list must contain exactly the ad's current original picture URLs.
+// @Tags advertisements
+// @Accept json
+// @Produce json
+// @Param id path int true "Ad ID"
+// @Param pictures body PicturesOrderInput true "Picture URLs in the new order"
+// @Success 200 {object} models.AdPictures "Pictures of the ad"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Not the owner of the ad"
+// @Failure 404 {object} string "Ad not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /ads/{id}/pictures [put]
+func (s *Server) ReorderAdPictures(w http.ResponseWriter, r *http.Request) {
+	ad, ok := s.findAd(w, r)
+	if !ok {
+		return
+	}
+
+	if !isAdOwner(r, ad) {
+		utils.RespondWithError(w, http.StatusForbidden, "Not the owner of the ad")
+		return
+	}
+
+	var input PicturesOrderInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if !isPermutation(input.Pictures, ad.Pictures) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	pictures, err := s.repos.Ads.SetPictures(r.Context(), ad.ID, input.Pictures)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Ad not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntityAd, ad.ID, models.AuditUpdate, pictureRecord(ad.Pictures), pictureRecord(pictures))
+	s.publishAdEvent(r, events.AdUpdated, ad.ID)
+
+	s.respondWithPictures(w, pictures)
+}
+
+func isPermutation(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// deletePictureFiles removes the stored files behind picture URLs. Files
+// that do not belong to the storage are skipped.
+func (s *Server) deletePictureFiles(r *http.Request, urls []string) {
+	for _, url := range urls {
+		if key, ok := s.files.Key(url); ok {
+			s.deletePictureKey(r, key)
+		}
+	}
+}
+
+// deletePictureKey removes a stored picture and its variants. Files that
+// are already gone are skipped.
+func (s *Server) deletePictureKey(r *http.Request, key string) {
+	keys := []string{key}
+	for _, variant := range images.Variants {
+		keys = append(keys, images.VariantKey(key, variant.Name))
+	}
+
+	for _, key := range keys {
+		err := s.files.Delete(r.Context(), key)
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error deleting picture %s: %v", key, err)
+		}
+	}
+}
+
+// pictures expands picture URLs into the original and its variants.
+// Pictures that are not in the storage have no variants, so the original
+// URL stands in for them.
+func (s *Server) pictures(urls []string) []models.Picture {
+	pictures := make([]models.Picture, len(urls))
+	for i, url := range urls {
+		pictures[i] = models.Picture{Original: url, Medium: url, Thumbnail: url}
+		if _, ok := s.files.Key(url); ok {
+			pictures[i].Medium = images.VariantKey(url, images.Medium.Name)
+			pictures[i].Thumbnail = images.VariantKey(url, images.Thumbnail.Name)
+		}
+	}
+	return pictures
+}
+
+func (s *Server) respondWithPictures(w http.ResponseWriter, pictures []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(models.AdPictures{Pictures: s.pictures(pictures)})
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/storage"
+)
+
+// uploadPictures posts n small JPEGs to the ad's pictures.
+func uploadPictures(t *testing.T, ts *testServer, adID uint, token string, n int) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for i := 0; i < n; i++ {
+		part, err := form.CreateFormFile("pictures", fmt.Sprintf("%d.jpg", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := jpeg.Encode(part, image.NewRGBA(image.Rect(0, 0, 8, 8)), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", fmt.Sprintf("/ads/%d/pictures", adID), &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return ts.serve(req)
+}
+
+func TestUploadPicturesLimit(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	id := ts.ad(seller, adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500))
+
+	rec := uploadPictures(t, ts, id, seller, maxPicturesPerAd-1)
+	expectStatus(t, rec, http.StatusOK)
+	var uploaded models.AdPictures
+	decode(t, rec, &uploaded)
+	if len(uploaded.Pictures) != maxPicturesPerAd-1 {
+		t.Fatalf("uploaded %d pictures, want %d", len(uploaded.Pictures), maxPicturesPerAd-1)
+	}
+	if !strings.HasPrefix(uploaded.Pictures[0].Original, "/media/") {
+		t.Fatalf("picture URL %q is not under the media URL", uploaded.Pictures[0].Original)
+	}
+	expectStatus(t, ts.do("GET", uploaded.Pictures[0].Thumbnail, "", nil), http.StatusOK)
+
+	expectStatus(t, uploadPictures(t, ts, id, seller, 2), http.StatusBadRequest)
+	expectStatus(t, uploadPictures(t, ts, id, seller, 1), http.StatusOK)
+}
+
+func TestAppendPicturesLimitIsAtomic(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	_, seller := ts.user("seller", models.RoleUser)
+	id := ts.ad(seller, adInput("Rusty spade", ts.subcategory("Garden", "Tools"), 500))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		appended int
+	)
+	for i := 0; i < 3*maxPicturesPerAd; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := ts.repos.Ads.AppendPictures(ctx, id, []string{fmt.Sprintf("/media/%d.jpg", i)}, maxPicturesPerAd)
+			switch {
+			case err == nil:
+				mu.Lock()
+				appended++
+				mu.Unlock()
+			case !errors.Is(err, repository.ErrLimit):
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	ad, err := ts.repos.Ads.Get(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if appended != maxPicturesPerAd || len(ad.Pictures) != maxPicturesPerAd {
+		t.Fatalf("appended %d, ad has %d pictures, want %d", appended, len(ad.Pictures), maxPicturesPerAd)
+	}
+}
+
+func TestMediaPrefix(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+		ok      bool
+	}{
+		{"/media/", "/media/", true},
+		{"/static/uploads/", "/static/uploads/", true},
+		{"https://cdn.example.com/files/", "/files/", true},
+		{"/media", "", false},
+		{"media/", "", false},
+	}
+	for _, test := range tests {
+		got, ok := mediaPrefix(storage.NewMemory(test.baseURL))
+		if got != test.want || ok != test.ok {
+			t.Errorf("mediaPrefix(%q) = %q, %v, want %q, %v", test.baseURL, got, ok, test.want, test.ok)
+		}
+	}
+}
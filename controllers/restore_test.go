@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+func TestRestoreTakenKey(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	_, admin := ts.user("admin", models.RoleAdmin)
+	garden := models.Category{Name: "Garden"}
+	if err := ts.repos.Categories.Create(ctx, &garden); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path   string
+		create func() uint
+		delete func(id uint) error
+	}{
+		{"/users", func() uint {
+			id, _ := ts.user("seller", models.RoleUser)
+			return id
+		}, func(id uint) error {
+			_, err := ts.repos.Users.Delete(ctx, id)
+			return err
+		}},
+		{"/categories", func() uint {
+			category := models.Category{Name: "Plants"}
+			if err := ts.repos.Categories.Create(ctx, &category); err != nil {
+				t.Fatal(err)
+			}
+			return category.ID
+		}, func(id uint) error {
+			_, err := ts.repos.Categories.Delete(ctx, id, repository.DeleteOptions{})
+			return err
+		}},
+		{"/subcategories", func() uint {
+			subcategory := models.Subcategory{Name: "Tools", CategoryID: garden.ID}
+			if err := ts.repos.Subcategories.Create(ctx, &subcategory); err != nil {
+				t.Fatal(err)
+			}
+			return subcategory.ID
+		}, func(id uint) error {
+			_, err := ts.repos.Subcategories.Delete(ctx, id, repository.DeleteOptions{})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			deleted := test.create()
+			if err := test.delete(deleted); err != nil {
+				t.Fatal(err)
+			}
+
+			taken := test.create()
+			restore := fmt.Sprintf("%s/%d/restore", test.path, deleted)
+			expectStatus(t, ts.do("POST", restore, admin, nil), http.StatusConflict)
+
+			if err := test.delete(taken); err != nil {
+				t.Fatal(err)
+			}
+			expectStatus(t, ts.do("POST", restore, admin, nil), http.StatusNoContent)
+		})
+	}
+}
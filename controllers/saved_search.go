@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+// SavedSearchInput describes the ads a user wants to be alerted about.
+// Criteria left out match every ad; lat, lon and radius go together.
+// A category also matches the ads in the categories below it.
+type SavedSearchInput struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	CategoryID    *uint    `json:"category_id"`
+	SubcategoryID *uint    `json:"subcategory_id"`
+	PriceMin      *int64   `json:"price_min" validate:"omitempty,gte=0"`
+	PriceMax      *int64   `json:"price_max" validate:"omitempty,gte=0"`
+	Currency      string   `json:"currency" validate:"omitempty,iso4217"`
+	Query         string   `json:"query" validate:"max=200"`
+	Lat           *float64 `json:"lat" validate:"required_with=Lon Radius,omitempty,latitude"`
+	Lon           *float64 `json:"lon" validate:"required_with=Lat Radius,omitempty,longitude"`
+	Radius        *float64 `json:"radius" validate:"required_with=Lat Lon,omitempty,gt=0"`
+}
+
+// GetSavedSearches godoc
+// @Summary Get a user's saved searches
+// @Description Retrieves the searches the user is alerted about. Users can only see their own saved searches.
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} models.SavedSearch "List of saved searches"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot access another user's saved searches"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/saved-searches [get]
+func (s *Server) GetSavedSearches(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot access another user's saved searches")
+		return
+	}
+
+	searches, err := s.repos.SavedSearches.List(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if searches == nil {
+		searches = []models.SavedSearch{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(searches)
+}
+
+// GetSavedSearch godoc
+// @Summary Get a saved search
+// @Description Retrieves one of the user's saved searches
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param search_id path int true "Saved search ID"
+// @Success 200 {object} models.SavedSearch "Saved search"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot access another user's saved searches"
+// @Failure 404 {object} string "Saved search not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/saved-searches/{search_id} [get]
+func (s *Server) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, ok := s.findSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(search)
+}
+
+// CreateSavedSearch godoc
+// @Summary Save a search
+// @Description Saves a search. The user is notified of every new ad that matches it.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param search body SavedSearchInput true "Search criteria"
+// @Success 201 {object} models.SavedSearch "Saved search"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's saved searches"
+// @Failure 404 {object} string "Category or subcategory not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/saved-searches [post]
+func (s *Server) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot change another user's saved searches")
+		return
+	}
+
+	input, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	search := models.SavedSearch{UserID: id}
+	input.apply(&search)
+
+	if err := s.repos.SavedSearches.Create(r.Context(), &search); err != nil {
+		respondWithSavedSearchError(w, err)
+		return
+	}
+	s.audit(r, models.EntitySavedSearch, search.ID, models.AuditCreate, nil, search)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(search)
+}
+
+// UpdateSavedSearch godoc
+// @Summary Update a saved search
+// @Description Replaces the name and criteria of a saved search
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param search_id path int true "Saved search ID"
+// @Param search body SavedSearchInput true "Search criteria"
+// @Success 200 {object} models.SavedSearch "Saved search"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's saved searches"
+// @Failure 404 {object} string "Saved search, category or subcategory not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/saved-searches/{search_id} [put]
+func (s *Server) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, ok := s.findSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	input, ok := decodeSavedSearch(w, r)
+	if !ok {
+		return
+	}
+	before := search
+	input.apply(&search)
+
+	if err := s.repos.SavedSearches.Update(r.Context(), &search); err != nil {
+		respondWithSavedSearchError(w, err)
+		return
+	}
+	s.audit(r, models.EntitySavedSearch, search.ID, models.AuditUpdate, before, search)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(search)
+}
+
+// DeleteSavedSearch godoc
+// @Summary Delete a saved search
+// @Description Deletes a saved search together with its notifications
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Param search_id path int true "Saved search ID"
+// @Success 204 "Saved search deleted"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot change another user's saved searches"
+// @Failure 404 {object} string "Saved search not found"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/saved-searches/{search_id} [delete]
+func (s *Server) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	search, ok := s.findSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.repos.SavedSearches.Delete(r.Context(), search.ID); err != nil {
+		respondWithSavedSearchError(w, err)
+		return
+	}
+	s.audit(r, models.EntitySavedSearch, search.ID, models.AuditDelete, search, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findSavedSearch loads the saved search named by the search_id path
+// variable if it belongs to the authenticated user named by id.
+func (s *Server) findSavedSearch(w http.ResponseWriter, r *http.Request) (models.SavedSearch, bool) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot access another user's saved searches")
+		return models.SavedSearch{}, false
+	}
+
+	searchID, ok := pathID(r, "search_id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Saved search not found")
+		return models.SavedSearch{}, false
+	}
+
+	search, err := s.repos.SavedSearches.Get(r.Context(), searchID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return models.SavedSearch{}, false
+	}
+	if err != nil || search.UserID != id {
+		utils.RespondWithError(w, http.StatusNotFound, "Saved search not found")
+		return models.SavedSearch{}, false
+	}
+
+	return search, true
+}
+
+func decodeSavedSearch(w http.ResponseWriter, r *http.Request) (SavedSearchInput, bool) {
+	var input SavedSearchInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return input, false
+	}
+	input.Currency = strings.ToUpper(input.Currency)
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return input, false
+	}
+	if input.PriceMin != nil && input.PriceMax != nil && *input.PriceMin > *input.PriceMax {
+		utils.RespondWithError(w, http.StatusBadRequest, "price_min exceeds price_max")
+		return input, false
+	}
+	if input.Radius != nil && *input.Radius > maxSearchRadius {
+		utils.RespondWithError(w, http.StatusBadRequest, "radius is too large")
+		return input, false
+	}
+
+	return input, true
+}
+
+func (input SavedSearchInput) apply(search *models.SavedSearch) {
+	search.Name = input.Name
+	search.CategoryID = input.CategoryID
+	search.SubcategoryID = input.SubcategoryID
+	search.PriceMin = input.PriceMin
+	search.PriceMax = input.PriceMax
+	search.Currency = input.Currency
+	search.Query = strings.TrimSpace(input.Query)
+	search.Lat = input.Lat
+	search.Lon = input.Lon
+	search.Radius = input.Radius
+}
+
+func respondWithSavedSearchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrForeignKey):
+		utils.RespondWithError(w, http.StatusNotFound, "Category or subcategory not found")
+	case errors.Is(err, repository.ErrNotFound):
+		utils.RespondWithError(w, http.StatusNotFound, "Saved search not found")
+	default:
+		log.Printf("Error saving search: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+	}
+}
@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/alerts"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/repository/memory"
+	"github.com/sciphilib/go-dacha/storage"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer runs the handlers on the in-memory repositories and storage.
+type testServer struct {
+	t       *testing.T
+	repos   repository.Repositories
+	files   *storage.Memory
+	broker  events.Broker
+	matcher *alerts.Matcher
+	auth    AuthConfig
+	handler http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	repos := memory.New()
+	files := storage.NewMemory("/media/")
+	broker := events.NewMemory()
+	auth := AuthConfig{
+		SigningKey:      []byte("test secret"),
+		AccessTokenTTL:  defaultAccessTokenTTL,
+		RefreshTokenTTL: defaultRefreshTokenTTL,
+	}
+
+	matcher := alerts.NewMatcher(repos, broker)
+
+	return &testServer{
+		t:       t,
+		repos:   repos,
+		files:   files,
+		broker:  broker,
+		matcher: matcher,
+		auth:    auth,
+		handler: New(repos, files, broker, matcher, auth, AdConfig{TTL: defaultAdTTL}),
+	}
+}
+
+// do sends a request with body encoded as JSON, authenticated with token
+// unless it is empty.
+func (ts *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	ts.t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			ts.t.Fatal(err)
+		}
+	}
+
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return ts.serve(req)
+}
+
+func (ts *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ts.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// user stores a user with the role and returns their ID and an access
+// token.
+func (ts *testServer) user(name, role string) (uint, string) {
+	ts.t.Helper()
+
+	user := models.User{
+		Name:        name,
+		Email:       name + "@example.com",
+		PhoneNumber: "+" + name,
+		Role:        role,
+	}
+	if err := ts.repos.Users.Create(context.Background(), &user); err != nil {
+		ts.t.Fatal(err)
+	}
+
+	token, err := ts.auth.GenerateToken(user)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	return user.ID, token
+}
+
+// subcategory stores a category with one subcategory and returns the
+// subcategory ID.
+func (ts *testServer) subcategory(category, name string) uint {
+	ts.t.Helper()
+
+	ctx := context.Background()
+	c := models.Category{Name: category}
+	if err := ts.repos.Categories.Create(ctx, &c); err != nil {
+		ts.t.Fatal(err)
+	}
+	sc := models.Subcategory{Name: name, CategoryID: c.ID}
+	if err := ts.repos.Subcategories.Create(ctx, &sc); err != nil {
+		ts.t.Fatal(err)
+	}
+	return sc.ID
+}
+
+// ad creates an ad through POST /ads and returns its ID.
+func (ts *testServer) ad(token string, input map[string]interface{}) uint {
+	ts.t.Helper()
+
+	rec := ts.do("POST", "/ads", token, input)
+	if rec.Code != http.StatusOK {
+		ts.t.Fatalf("POST /ads: status %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID uint `json:"id"`
+	}
+	decode(ts.t, rec, &created)
+	return created.ID
+}
+
+// adInput returns a valid ad body in the subcategory.
+func adInput(title string, subcategoryID uint, amount int64) map[string]interface{} {
+	return map[string]interface{}{
+		"title":          title,
+		"subcategory_id": subcategoryID,
+		"price":          map[string]interface{}{"amount": amount, "currency": "EUR"},
+		"datetime":       time.Now().UTC().Format(time.RFC3339),
+		"location":       map[string]interface{}{"type": "Point", "coordinates": []float64{13.4, 52.5}},
+	}
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body, err)
+	}
+}
+
+func expectStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status %d, want %d: %s", rec.Code, want, rec.Body)
+	}
+}
+
+func TestRegisterAndAuthenticate(t *testing.T) {
+	ts := newTestServer(t)
+
+	rec := ts.do("POST", "/users/registration", "", map[string]interface{}{
+		"name":         "alice",
+		"email":        "alice@example.com",
+		"password":     "secret",
+		"phone_number": "+10000000000",
+	})
+	expectStatus(t, rec, http.StatusOK)
+	var registered TokenPair
+	decode(t, rec, &registered)
+	if registered.ID == 0 || registered.Token == "" || registered.RefreshToken == "" {
+		t.Fatalf("registration returned %+v", registered)
+	}
+
+	rec = ts.do("POST", "/users/authentication", "", map[string]string{"email": "alice@example.com", "password": "wrong"})
+	expectStatus(t, rec, http.StatusBadRequest)
+
+	rec = ts.do("POST", "/users/authentication", "", map[string]string{"email": "alice@example.com", "password": "secret"})
+	expectStatus(t, rec, http.StatusOK)
+	var authenticated TokenPair
+	decode(t, rec, &authenticated)
+	if authenticated.ID != registered.ID {
+		t.Fatalf("authenticated as %d, want %d", authenticated.ID, registered.ID)
+	}
+}
+
+// TestConcurrentWrites runs writes that validate their input side by side
+// so that go test -race catches state shared between requests.
+func TestConcurrentWrites(t *testing.T) {
+	ts := newTestServer(t)
+	_, seller := ts.user("seller", models.RoleUser)
+	tools := ts.subcategory("Garden", "Tools")
+
+	codes := make(chan int, 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			codes <- ts.do("POST", "/ads", seller, adInput(fmt.Sprintf("Spade %d", i), tools, 500)).Code
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			codes <- ts.do("POST", "/users/registration", "", map[string]interface{}{
+				"name":         fmt.Sprintf("user%d", i),
+				"email":        fmt.Sprintf("user%d@example.com", i),
+				"password":     "secret",
+				"phone_number": fmt.Sprintf("+1000000000%d", i),
+			}).Code
+		}(i)
+	}
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("status %d, want %d", code, http.StatusOK)
+		}
+	}
+}
+
+func TestWriteRoutesRequireToken(t *testing.T) {
+	ts := newTestServer(t)
+
+	expectStatus(t, ts.do("POST", "/ads", "", adInput("Spade", 1, 100)), http.StatusUnauthorized)
+	expectStatus(t, ts.do("POST", "/ads", "not a token", adInput("Spade", 1, 100)), http.StatusUnauthorized)
+}
+
+func TestCategoryManagementRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	_, userToken := ts.user("alice", models.RoleUser)
+	_, adminToken := ts.user("root", models.RoleAdmin)
+
+	expectStatus(t, ts.do("POST", "/categories", userToken, map[string]string{"name": "Garden"}), http.StatusForbidden)
+	expectStatus(t, ts.do("POST", "/categories", adminToken, map[string]string{"name": "Garden"}), http.StatusOK)
+	expectStatus(t, ts.do("POST", "/categories", adminToken, map[string]string{"name": "Garden"}), http.StatusConflict)
+}
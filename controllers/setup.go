@@ -1,49 +1,120 @@
 package controllers
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/sciphilib/go-dacha/alerts"
 	_ "github.com/sciphilib/go-dacha/docs"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/storage"
 
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func New() http.Handler {
+// Server holds the dependencies of the HTTP handlers.
+type Server struct {
+	repos   repository.Repositories
+	files   storage.Storage
+	broker  events.Broker
+	matcher *alerts.Matcher
+	auth    AuthConfig
+	ads     AdConfig
+}
+
+func New(repos repository.Repositories, files storage.Storage, broker events.Broker, matcher *alerts.Matcher, auth AuthConfig, ads AdConfig) http.Handler {
+	s := &Server{repos: repos, files: files, broker: broker, matcher: matcher, auth: auth, ads: ads}
+
 	router := mux.NewRouter()
 
-	router.HandleFunc("/users", GetAllUsers).Methods("GET")
-	router.HandleFunc("/users/{id}", GetUser).Methods("GET")
-	router.HandleFunc("/users/{id}", UpdateUser).Methods("PUT")
-	router.HandleFunc("/users/registration", RegisterUser).Methods("POST")
-	router.HandleFunc("/users/authentication", AuthenticateUser).Methods("POST")
-
-	router.HandleFunc("/categories", GetAllCategories).Methods("GET")
-	router.HandleFunc("/categories/{id}", GetCategory).Methods("GET")
-	router.HandleFunc("/categories", CreateCategory).Methods("POST")
-	router.HandleFunc("/categories/{id}", UpdateCategory).Methods("PUT")
-	router.HandleFunc("/categories/{id}", DeleteCategory).Methods("DELETE")
-
-	router.HandleFunc("/subcategories", GetAllSubcategories).Methods("GET")
-	router.HandleFunc("/subcategories/{id}", GetSubcategory).Methods("GET")
-	router.HandleFunc("/subcategories", CreateSubcategory).Methods("POST")
-	router.HandleFunc("/subcategories/{id}", UpdateSubcategory).Methods("PUT")
-	router.HandleFunc("/subcategories/{id}", DeleteSubcategory).Methods("DELETE")
-
-	router.HandleFunc("/ads", GetAllAds).Methods("GET")
-	router.HandleFunc("/ads/newest", GetNewestAds).Methods("GET")
-	router.HandleFunc("/ads/{id}/nearest", GetNearestAds).Methods("GET")
-	router.HandleFunc("/ads/{id}", GetAd).Methods("GET")
-	router.HandleFunc("/ads", CreateAd).Methods("POST")
-	router.HandleFunc("/ads/{id}", UpdateAd).Methods("PUT")
-	router.HandleFunc("/ads/{id}", DeleteAd).Methods("DELETE")
+	router.HandleFunc("/users", s.OptionalAuth(s.GetAllUsers)).Methods("GET")
+	router.HandleFunc("/users/{id}", s.OptionalAuth(s.GetUser)).Methods("GET")
+	router.HandleFunc("/users/{id}", s.RequireAuth(s.UpdateUser)).Methods("PUT")
+	router.HandleFunc("/users/{id}", s.RequireAuth(s.DeleteUser)).Methods("DELETE")
+	router.HandleFunc("/users/{id}/restore", s.RequireRole(adminOnly, s.RestoreUser)).Methods("POST")
+	router.HandleFunc("/users/{id}/favorites", s.RequireAuth(s.GetFavorites)).Methods("GET")
+	router.HandleFunc("/users/{id}/favorites", s.RequireAuth(s.AddFavorite)).Methods("POST")
+	router.HandleFunc("/users/{id}/favorites/{ad_id}", s.RequireAuth(s.RemoveFavorite)).Methods("DELETE")
+	router.HandleFunc("/users/{id}/saved-searches", s.RequireAuth(s.GetSavedSearches)).Methods("GET")
+	router.HandleFunc("/users/{id}/saved-searches", s.RequireAuth(s.CreateSavedSearch)).Methods("POST")
+	router.HandleFunc("/users/{id}/saved-searches/{search_id}", s.RequireAuth(s.GetSavedSearch)).Methods("GET")
+	router.HandleFunc("/users/{id}/saved-searches/{search_id}", s.RequireAuth(s.UpdateSavedSearch)).Methods("PUT")
+	router.HandleFunc("/users/{id}/saved-searches/{search_id}", s.RequireAuth(s.DeleteSavedSearch)).Methods("DELETE")
+	router.HandleFunc("/users/{id}/notifications", s.RequireAuth(s.GetNotifications)).Methods("GET")
+	router.HandleFunc("/users/{id}/notifications/{notification_id}/read", s.RequireAuth(s.MarkNotificationRead)).Methods("POST")
+	router.HandleFunc("/users/{id}/role", s.RequireRole(adminOnly, s.UpdateUserRole)).Methods("PUT")
+	router.HandleFunc("/users/registration", s.RegisterUser).Methods("POST")
+	router.HandleFunc("/users/authentication", s.AuthenticateUser).Methods("POST")
+	router.HandleFunc("/users/token/refresh", s.RefreshAccessToken).Methods("POST")
+	router.HandleFunc("/users/token/revoke", s.RevokeRefreshToken).Methods("POST")
+
+	router.HandleFunc("/categories", s.GetAllCategories).Methods("GET")
+	router.HandleFunc("/categories/tree", s.GetCategoryTree).Methods("GET")
+	router.HandleFunc("/categories/{id}", s.GetCategory).Methods("GET")
+	router.HandleFunc("/categories/{id}/descendants", s.GetCategoryDescendants).Methods("GET")
+	router.HandleFunc("/categories", s.RequireRole(adminOnly, s.CreateCategory)).Methods("POST")
+	router.HandleFunc("/categories/{id}", s.RequireRole(adminOnly, s.UpdateCategory)).Methods("PUT")
+	router.HandleFunc("/categories/{id}", s.RequireRole(adminOnly, s.DeleteCategory)).Methods("DELETE")
+	router.HandleFunc("/categories/{id}/restore", s.RequireRole(adminOnly, s.RestoreCategory)).Methods("POST")
+
+	router.HandleFunc("/subcategories", s.GetAllSubcategories).Methods("GET")
+	router.HandleFunc("/subcategories/{id}", s.GetSubcategory).Methods("GET")
+	router.HandleFunc("/subcategories", s.RequireRole(adminOnly, s.CreateSubcategory)).Methods("POST")
+	router.HandleFunc("/subcategories/{id}", s.RequireRole(adminOnly, s.UpdateSubcategory)).Methods("PUT")
+	router.HandleFunc("/subcategories/{id}", s.RequireRole(adminOnly, s.DeleteSubcategory)).Methods("DELETE")
+	router.HandleFunc("/subcategories/{id}/restore", s.RequireRole(adminOnly, s.RestoreSubcategory)).Methods("POST")
+
+	router.HandleFunc("/ads", s.OptionalAuth(s.GetAllAds)).Methods("GET")
+	router.HandleFunc("/ads/newest", s.OptionalAuth(s.GetNewestAds)).Methods("GET")
+	router.HandleFunc("/ads/search", s.OptionalAuth(s.SearchAds)).Methods("GET")
+	router.HandleFunc("/ads/{id}/nearest", s.OptionalAuth(s.GetNearestAds)).Methods("GET")
+	router.HandleFunc("/ads/{id}", s.OptionalAuth(s.GetAd)).Methods("GET")
+	router.HandleFunc("/ads", s.RequireAuth(s.CreateAd)).Methods("POST")
+	router.HandleFunc("/ads/{id}", s.RequireAuth(s.UpdateAd)).Methods("PUT")
+	router.HandleFunc("/ads/{id}", s.RequireAuth(s.DeleteAd)).Methods("DELETE")
+	router.HandleFunc("/ads/{id}/restore", s.RequireRole(adminOnly, s.RestoreAd)).Methods("POST")
+	router.HandleFunc("/ads/{id}/status", s.RequireAuth(s.UpdateAdStatus)).Methods("PUT")
+	router.HandleFunc("/ads/{id}/renew", s.RequireAuth(s.RenewAd)).Methods("POST")
+	router.HandleFunc("/ads/{id}/pictures", s.RequireAuth(s.UploadAdPictures)).Methods("POST")
+	router.HandleFunc("/ads/{id}/pictures", s.RequireAuth(s.ReorderAdPictures)).Methods("PUT")
+	router.HandleFunc("/ads/{id}/pictures/{name}", s.RequireAuth(s.DeleteAdPicture)).Methods("DELETE")
+	router.HandleFunc("/ads/{id}/conversations", s.RequireAuth(s.StartConversation)).Methods("POST")
+	router.HandleFunc("/ads/{id}/report", s.RequireAuth(s.ReportAd)).Methods("POST")
+	router.HandleFunc("/ads/{id}/moderation", s.RequireRole(moderators, s.GetModerationActions)).Methods("GET")
+	router.HandleFunc("/ads/{id}/moderation", s.RequireRole(moderators, s.ModerateAd)).Methods("POST")
+	router.HandleFunc("/moderation/queue", s.RequireRole(moderators, s.GetModerationQueue)).Methods("GET")
+
+	router.HandleFunc("/conversations", s.RequireAuth(s.GetConversations)).Methods("GET")
+	router.HandleFunc("/conversations/{id}/messages", s.RequireAuth(s.GetMessages)).Methods("GET")
+	router.HandleFunc("/conversations/{id}/messages", s.RequireAuth(s.PostMessage)).Methods("POST")
+	router.HandleFunc("/conversations/{id}/read", s.RequireAuth(s.MarkConversationRead)).Methods("POST")
+
+	router.HandleFunc("/audit", s.RequireRole(adminOnly, s.GetAuditLog)).Methods("GET")
+
+	router.HandleFunc("/events", queryToken(s.RequireAuth(s.StreamEvents))).Methods("GET")
+
+	if handler, ok := files.(http.Handler); ok {
+		if prefix, ok := mediaPrefix(files); ok {
+			router.PathPrefix(prefix).Handler(http.StripPrefix(prefix, handler))
+		} else {
+			log.Printf("Media URL %q is not a local path ending with a slash, files are not served", files.URL(""))
+		}
+	}
 
 	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
 
-	loggedRouter := Logger(router)
+	loggedRouter := RequestID(Logger(router))
 
 	return loggedRouter
 }
@@ -52,10 +123,14 @@ func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
-		log.Printf("Started %s %s", r.Method, r.URL.Path)
+		log.Printf("Started %s %s (request %s)", r.Method, r.URL.Path, RequestIDFromContext(r.Context()))
 		log.Println("Headers:")
 		for name, values := range r.Header {
-			valueString := fmt.Sprintf("%s: %s", name, values[0])
+			value := values[0]
+			if name == "Authorization" {
+				value = "[redacted]"
+			}
+			valueString := fmt.Sprintf("%s: %s", name, value)
 			log.Println(valueString)
 		}
 
@@ -64,3 +139,49 @@ func Logger(next http.Handler) http.Handler {
 		log.Printf("Completed %s in %v", r.URL.Path, time.Since(start))
 	})
 }
+
+// requestIDHeader carries the ID of a request. A client or proxy may set
+// it; otherwise one is generated. Either way the response echoes it.
+const requestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID tags every request with an ID, which ties the audit entries
+// and log lines of one request together.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			raw := make([]byte, 16)
+			if _, err := rand.Read(raw); err != nil {
+				log.Printf("Error generating request ID: %v", err)
+			}
+			id = hex.EncodeToString(raw)
+		}
+
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// RequestIDFromContext returns the ID RequestID gave the request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// mediaPrefix returns the path of the storage's base URL (MEDIA_URL),
+// which is where files are served when the storage can serve them itself.
+func mediaPrefix(files storage.Storage) (string, bool) {
+	u, err := url.Parse(files.URL(""))
+	if err != nil || !strings.HasPrefix(u.Path, "/") || !strings.HasSuffix(u.Path, "/") {
+		return "", false
+	}
+	return u.Path, true
+}
+
+// pathID parses the numeric ID in the named route variable.
+func pathID(r *http.Request, name string) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)[name], 10, 0)
+	return uint(id), err == nil
+}
@@ -3,13 +3,14 @@ package controllers
 import (
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/gorilla/mux"
+	"github.com/sciphilib/go-dacha/events"
 	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
 	"github.com/sciphilib/go-dacha/utils"
-	"gorm.io/gorm"
 )
 
 // GetAllSubcategories godoc
@@ -18,25 +19,33 @@ import (
 // @Tags subcategories
 // @Accept json
 // @Produce json
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {array} models.SubcategoryResponse "List of subcategories"
 // @Router /subcategories [get]
-func GetAllSubcategories(w http.ResponseWriter, r *http.Request) {
-	var subcategories []models.Subcategory
-	err := models.DB.
-		Preload("Category").
-		Find(&subcategories).Error
+func (s *Server) GetAllSubcategories(w http.ResponseWriter, r *http.Request) {
+	subcategories, err := s.repos.Subcategories.List(r.Context())
 
 	if err != nil {
 		utils.RespondWithError(w, http.StatusInternalServerError, "Encoding error")
 		return
 	}
 
+	l, err := s.localize(r)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	l.renameSubcategories(subcategories)
+
 	var formattedSubcategories []map[string]interface{}
 	for _, subcategory := range subcategories {
 		formattedSubcategory := map[string]interface{}{
-			"id":       subcategory.ID,
-			"name":     subcategory.Name,
-			"category": subcategory.Category.Name,
+			"id":               subcategory.ID,
+			"name":             subcategory.Name,
+			"category":         subcategory.Category.Name,
+			"attribute_schema": subcategory.AttributeSchema,
+			"translations":     subcategory.Translations,
 		}
 		formattedSubcategories = append(formattedSubcategories, formattedSubcategory)
 	}
@@ -55,33 +64,29 @@ func GetAllSubcategories(w http.ResponseWriter, r *http.Request) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Subcategory ID"
+// @Param Accept-Language header string false "Preferred languages for category and subcategory names"
 // @Success 200 {object} models.SubcategoryResponse "Subcategory found"
 // @Failure 404 {object} string "Subcategory not found"
 // @Router /subcategories/{id} [get]
-func GetSubcategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-
-	var subcategory models.Subcategory
-
-	err := models.DB.
-		Preload("Category").
-		First(&subcategory, id).Error
+func (s *Server) GetSubcategory(w http.ResponseWriter, r *http.Request) {
+	subcategory, ok := s.findSubcategory(w, r)
+	if !ok {
+		return
+	}
 
+	l, err := s.localize(r)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.RespondWithError(w, http.StatusNotFound, "Subcategory not found")
-			return
-		} else {
-			utils.RespondWithError(w, http.StatusInternalServerError, "Encoding error")
-			return
-		}
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
 	response := map[string]interface{}{
-		"id":       subcategory.ID,
-		"name":     subcategory.Name,
-		"category": subcategory.Category.Name,
+		"id":               subcategory.ID,
+		"name":             l.subcategory(subcategory.ID, subcategory.Name),
+		"category":         l.category(subcategory.CategoryID, subcategory.Category.Name),
+		"attribute_schema": subcategory.AttributeSchema,
+		"translations":     subcategory.Translations,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -91,9 +96,40 @@ func GetSubcategory(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// findSubcategory loads the subcategory named by the id route variable
+// and responds with an error if it cannot.
+func (s *Server) findSubcategory(w http.ResponseWriter, r *http.Request) (models.Subcategory, bool) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Subcategory not found")
+		return models.Subcategory{}, false
+	}
+
+	subcategory, err := s.repos.Subcategories.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "Subcategory not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return models.Subcategory{}, false
+	}
+
+	return subcategory, true
+}
+
+// SubcategoryInput names a subcategory in models.DefaultLocale and the
+// category it belongs to.
 type SubcategoryInput struct {
-	Category string `json:"category"`
-	Name     string `json:"name"`
+	CategoryID uint   `json:"category_id"`
+	Name       string `json:"name"`
+	// Translations holds the name in other locales.
+	Translations models.Translations `json:"translations"`
+	// AttributeSchema defines the attributes of the ads in the
+	// subcategory. Ads already in it are not checked again. An update
+	// without it keeps the current schema.
+	AttributeSchema *models.AttributeSchema `json:"attribute_schema"`
 }
 
 // CreateSubcategory godoc
@@ -104,10 +140,12 @@ type SubcategoryInput struct {
 // @Produce json
 // @Param subcategory body SubcategoryInput true "Subcategory creation data"
 // @Success 200 {object} models.Subcategory "Subcategory created"
-// @Failure 400 {object} string "Invalid JSON payload or validation error"
-// @Failure 403 {object} string "Unknown category"
+// @Failure 400 {object} string "Invalid JSON payload, validation error, invalid attribute schema or invalid translations"
+// @Failure 403 {object} string "Unknown category or insufficient permissions"
+// @Failure 401 {object} string "Unauthorized"
+// @Security BearerAuth
 // @Router /subcategories [post]
-func CreateSubcategory(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateSubcategory(w http.ResponseWriter, r *http.Request) {
 	var input SubcategoryInput
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
@@ -119,21 +157,38 @@ func CreateSubcategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var category models.Category
-	if err := models.DB.Where("name = ?", input.Category).First(&category).Error; err != nil {
+	schema := models.AttributeSchema{}
+	if input.AttributeSchema != nil {
+		schema = *input.AttributeSchema
+	}
+	if err := schema.Check(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid attribute schema: "+err.Error())
+		return
+	}
+
+	if err := input.Translations.Check(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid translations: "+err.Error())
+		return
+	}
+
+	category, err := s.repos.Categories.Get(r.Context(), input.CategoryID)
+	if err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Unknown category")
 		return
 	}
 
 	subcategory := models.Subcategory{
-		Name:       input.Name,
-		CategoryID: category.ID,
+		Name:            input.Name,
+		CategoryID:      category.ID,
+		AttributeSchema: schema,
+		Translations:    input.Translations,
 	}
 
-	if err := models.DB.Create(&subcategory).Error; err != nil {
+	if err := s.repos.Subcategories.Create(r.Context(), &subcategory); err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Failed to create new subcategory")
 		return
 	}
+	s.audit(r, models.EntitySubcategory, subcategory.ID, models.AuditCreate, nil, subcategory)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -142,23 +197,22 @@ func CreateSubcategory(w http.ResponseWriter, r *http.Request) {
 
 // UpdateSubcategory godoc
 // @Summary Update a subcategory
-// @Description Updates an existing subcategory by ID
+// @Description Updates an existing subcategory by ID. Without attribute_schema the current schema is kept.
 // @Tags subcategories
 // @Accept json
 // @Produce json
 // @Param id path int true "Subcategory ID"
 // @Param subcategory body SubcategoryInput true "Subcategory update data"
 // @Success 200 {object} models.Subcategory "Subcategory updated"
-// @Failure 400 {object} string "Invalid JSON payload or validation error"
-// @Failure 403 {object} string "Unknown category"
+// @Failure 400 {object} string "Invalid JSON payload, validation error, invalid attribute schema or invalid translations"
+// @Failure 403 {object} string "Unknown category or insufficient permissions"
 // @Failure 404 {object} string "Subcategory not found"
+// @Failure 401 {object} string "Unauthorized"
+// @Security BearerAuth
 // @Router /subcategories/{id} [put]
-func UpdateSubcategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var subcategory models.Subcategory
-
-	if err := models.DB.Where("id = ?", id).First(&subcategory).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Subcategory not found")
+func (s *Server) UpdateSubcategory(w http.ResponseWriter, r *http.Request) {
+	subcategory, ok := s.findSubcategory(w, r)
+	if !ok {
 		return
 	}
 
@@ -173,19 +227,38 @@ func UpdateSubcategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var category models.Category
-	if err := models.DB.Where("name = ?", input.Category).First(&category).Error; err != nil {
+	if input.AttributeSchema != nil {
+		if err := input.AttributeSchema.Check(); err != nil {
+			utils.RespondWithError(w, http.StatusBadRequest, "Invalid attribute schema: "+err.Error())
+			return
+		}
+	}
+
+	if err := input.Translations.Check(); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid translations: "+err.Error())
+		return
+	}
+
+	category, err := s.repos.Categories.Get(r.Context(), input.CategoryID)
+	if err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Unknown category")
 		return
 	}
 
+	before := subcategory
 	subcategory.Name = input.Name
 	subcategory.CategoryID = category.ID
+	subcategory.Category = category
+	if input.AttributeSchema != nil {
+		subcategory.AttributeSchema = *input.AttributeSchema
+	}
+	subcategory.Translations = input.Translations
 
-	if err := models.DB.Save(&subcategory).Error; err != nil {
+	if err := s.repos.Subcategories.Update(r.Context(), &subcategory); err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Failed to update subcategory")
 		return
 	}
+	s.audit(r, models.EntitySubcategory, subcategory.ID, models.AuditUpdate, before, subcategory)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -194,25 +267,108 @@ func UpdateSubcategory(w http.ResponseWriter, r *http.Request) {
 
 // DeleteSubcategory godoc
 // @Summary Delete a subcategory
-// @Description Deletes an existing subcategory by ID
+// @Description Soft-deletes an existing subcategory by ID. A subcategory that has ads is only deleted with reassign_to, which moves them to another subcategory, or with cascade=true, which deletes them in the same transaction.
 // @Tags subcategories
 // @Accept json
 // @Produce json
 // @Param id path int true "Subcategory ID"
+// @Param reassign_to query int false "ID of the subcategory to move the ads to"
+// @Param cascade query bool false "Delete the ads too"
 // @Success 200 "Subcategory successfully deleted"
+// @Failure 400 {object} string "Invalid query parameters"
 // @Failure 404 {object} string "Subcategory not found"
+// @Failure 409 {object} models.DeleteConflict "Subcategory has ads"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
 // @Router /subcategories/{id} [delete]
-func DeleteSubcategory(w http.ResponseWriter, r *http.Request) {
-	id := mux.Vars(r)["id"]
-	var Subcategory models.Subcategory
+func (s *Server) DeleteSubcategory(w http.ResponseWriter, r *http.Request) {
+	subcategory, ok := s.findSubcategory(w, r)
+	if !ok {
+		return
+	}
 
-	if err := models.DB.Where("id = ?", id).First(&Subcategory).Error; err != nil {
-		utils.RespondWithError(w, http.StatusNotFound, "Subcategory not found")
+	opts, err := parseDeleteOptions(r.URL.Query())
+	if err != nil || opts.ReassignTo == subcategory.ID {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid query parameters")
 		return
 	}
+	if opts.ReassignTo != 0 {
+		if _, err := s.repos.Subcategories.Get(r.Context(), opts.ReassignTo); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				utils.RespondWithError(w, http.StatusBadRequest, "Subcategory to reassign to not found")
+			} else {
+				log.Printf("Request error: %v", err)
+				utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			}
+			return
+		}
+	}
 
-	models.DB.Delete(&Subcategory)
+	deleted, err := s.repos.Subcategories.Delete(r.Context(), subcategory.ID, opts)
+	if err != nil {
+		if errors.Is(err, repository.ErrForeignKey) {
+			s.respondWithDependents(w, r, "Subcategory has ads", s.repos.Subcategories.Dependents, subcategory.ID)
+		} else {
+			log.Printf("Error deleting subcategory: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+	s.audit(r, models.EntitySubcategory, subcategory.ID, models.AuditDelete, subcategory, nil)
+	s.auditDeleted(r, deleted)
+	for _, ad := range deleted.Ads {
+		s.publishAdEvent(r, events.AdDeleted, ad.ID)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 }
+
+// RestoreSubcategory godoc
+// @Summary Restore a deleted subcategory
+// @Description Brings back a deleted subcategory with the ads deleted together with it. Its category must be restored first.
+// @Tags subcategories
+// @Produce json
+// @Param id path int true "Subcategory ID"
+// @Success 204 "Subcategory restored"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Deleted subcategory not found"
+// @Failure 409 {object} string "The category of the subcategory is deleted, or another subcategory of the category has taken the name"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /subcategories/{id}/restore [post]
+func (s *Server) RestoreSubcategory(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Deleted subcategory not found")
+		return
+	}
+
+	if err := s.repos.Subcategories.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Deleted subcategory not found")
+		case errors.Is(err, repository.ErrForeignKey):
+			utils.RespondWithError(w, http.StatusConflict, "The category of the subcategory is deleted")
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "Another subcategory of the category has taken the name")
+		default:
+			log.Printf("Error restoring subcategory: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	subcategory, err := s.repos.Subcategories.Get(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntitySubcategory, id, models.AuditRestore, nil, subcategory)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestSubcategoriesOfNamesakeCategories(t *testing.T) {
+	ts := newTestServer(t)
+	_, admin := ts.user("root", models.RoleAdmin)
+
+	create := func(name string, parentID *uint) uint {
+		rec := ts.do("POST", "/categories", admin, CategoryInput{Name: name, ParentID: parentID})
+		expectStatus(t, rec, http.StatusOK)
+		var category models.Category
+		decode(t, rec, &category)
+		return category.ID
+	}
+	garden := create("Garden", nil)
+	house := create("House", nil)
+	gardenTools := create("Tools", &garden)
+	houseTools := create("Tools", &house)
+
+	rec := ts.do("POST", "/subcategories", admin, SubcategoryInput{CategoryID: houseTools, Name: "Drills"})
+	expectStatus(t, rec, http.StatusOK)
+	var subcategory models.Subcategory
+	decode(t, rec, &subcategory)
+	if subcategory.CategoryID != houseTools {
+		t.Errorf("subcategory created in category %d, want %d", subcategory.CategoryID, houseTools)
+	}
+
+	path := fmt.Sprintf("/subcategories/%d", subcategory.ID)
+	rec = ts.do("PUT", path, admin, SubcategoryInput{CategoryID: gardenTools, Name: "Drills"})
+	expectStatus(t, rec, http.StatusOK)
+	decode(t, rec, &subcategory)
+	if subcategory.CategoryID != gardenTools {
+		t.Errorf("subcategory moved to category %d, want %d", subcategory.CategoryID, gardenTools)
+	}
+
+	expectStatus(t, ts.do("POST", "/subcategories", admin, SubcategoryInput{CategoryID: 999, Name: "Saws"}), http.StatusForbidden)
+	expectStatus(t, ts.do("PUT", path, admin, SubcategoryInput{Name: "Drills"}), http.StatusForbidden)
+
+	reassign := fmt.Sprintf("/categories/%d?reassign_to=%d", house, garden)
+	expectStatus(t, ts.do("DELETE", reassign, admin, nil), http.StatusConflict)
+}
+
+func TestUpdateSubcategoryKeepsAttributeSchema(t *testing.T) {
+	ts := newTestServer(t)
+	_, admin := ts.user("root", models.RoleAdmin)
+	category := models.Category{Name: "Garden"}
+	if err := ts.repos.Categories.Create(context.Background(), &category); err != nil {
+		t.Fatal(err)
+	}
+
+	schema := models.AttributeSchema{{Name: "power_w", Type: models.AttributeNumber, Required: true}}
+	rec := ts.do("POST", "/subcategories", admin, SubcategoryInput{CategoryID: category.ID, Name: "Tools", AttributeSchema: &schema})
+	expectStatus(t, rec, http.StatusOK)
+	var subcategory models.Subcategory
+	decode(t, rec, &subcategory)
+	path := fmt.Sprintf("/subcategories/%d", subcategory.ID)
+
+	tests := []struct {
+		name string
+		body map[string]interface{}
+		want int
+	}{
+		{"omitted", map[string]interface{}{"category_id": category.ID, "name": "Power tools"}, 1},
+		{"null", map[string]interface{}{"category_id": category.ID, "name": "Tools", "attribute_schema": nil}, 1},
+		{"emptied", map[string]interface{}{"category_id": category.ID, "name": "Tools", "attribute_schema": []interface{}{}}, 0},
+	}
+	for _, test := range tests {
+		rec := ts.do("PUT", path, admin, test.body)
+		expectStatus(t, rec, http.StatusOK)
+		var updated models.Subcategory
+		decode(t, rec, &updated)
+		stored, err := ts.repos.Subcategories.Get(context.Background(), subcategory.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(updated.AttributeSchema) != test.want || len(stored.AttributeSchema) != test.want {
+			t.Errorf("%s schema: got %v, stored %v, want %d attributes", test.name, updated.AttributeSchema, stored.AttributeSchema, test.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/utils"
+)
+
+type TokenPair struct {
+	ID           uint   `json:"id"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken generates a refresh token for the user and returns it
+// together with the record to store.
+func (s *Server) newRefreshToken(user models.User) (string, models.RefreshToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", models.RefreshToken{}, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.auth.RefreshTokenTTL),
+	}
+	return refreshToken, record, nil
+}
+
+func (s *Server) tokenPair(user models.User, refreshToken string) (TokenPair, error) {
+	accessToken, err := s.auth.GenerateToken(user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	return TokenPair{
+		ID:           user.ID,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.auth.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// issueTokens signs a new access token for the user and stores a fresh
+// refresh token alongside it.
+func (s *Server) issueTokens(ctx context.Context, user models.User) (TokenPair, error) {
+	refreshToken, record, err := s.newRefreshToken(user)
+	if err != nil {
+		return TokenPair{}, err
+	}
+
+	if err := s.repos.RefreshTokens.Create(ctx, &record); err != nil {
+		return TokenPair{}, err
+	}
+
+	return s.tokenPair(user, refreshToken)
+}
+
+// RefreshAccessToken godoc
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access and refresh token pair. The presented refresh token is revoked; presenting it again revokes every session of the user.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param token body RefreshInput true "Refresh token"
+// @Success 200 {object} TokenPair "New token pair"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Invalid refresh token"
+// @Failure 500 {object} string "Internal Server Error"
+// @Router /users/token/refresh [post]
+func (s *Server) RefreshAccessToken(w http.ResponseWriter, r *http.Request) {
+	var input RefreshInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	stored, err := s.repos.RefreshTokens.GetByHash(r.Context(), hashToken(input.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	if stored.RevokedAt != nil {
+		// A rotated token showing up again means it has leaked.
+		s.revokeUserTokens(r.Context(), stored.UserID)
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Refresh token expired")
+		return
+	}
+
+	user, err := s.repos.Users.Get(r.Context(), stored.UserID)
+	if err != nil {
+		utils.RespondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	refreshToken, next, err := s.newRefreshToken(user)
+	if err == nil {
+		err = s.repos.RefreshTokens.Rotate(r.Context(), stored.ID, &next)
+	}
+
+	var pair TokenPair
+	if err == nil {
+		pair, err = s.tokenPair(user, refreshToken)
+	}
+
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(pair)
+}
+
+// RevokeRefreshToken godoc
+// @Summary Log out
+// @Description Revokes a refresh token so it can no longer be used
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param token body RefreshInput true "Refresh token"
+// @Success 204 "Refresh token revoked"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 500 {object} string "Internal Server Error"
+// @Router /users/token/revoke [post]
+func (s *Server) RevokeRefreshToken(w http.ResponseWriter, r *http.Request) {
+	var input RefreshInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	if err := s.repos.RefreshTokens.Revoke(r.Context(), hashToken(input.RefreshToken)); err != nil {
+		log.Printf("Error revoking refresh token: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) revokeUserTokens(ctx context.Context, userID uint) {
+	if err := s.repos.RefreshTokens.RevokeAllForUser(ctx, userID); err != nil {
+		log.Printf("Error revoking refresh tokens of user %d: %v", userID, err)
+	}
+}
@@ -9,30 +9,15 @@ import (
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/gorilla/mux"
 	"github.com/paulmach/orb"
 	"github.com/paulmach/orb/encoding/ewkb"
 	"github.com/paulmach/orb/geojson"
-	"github.com/sciphilib/go-dacha/common"
 	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
 	"github.com/sciphilib/go-dacha/utils"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-var (
-	validate *validator.Validate
-)
-
-const (
-	signingKey = "ldkfjalksdjflksj#32141#@@$!@"
-)
-
-type TokenClaims struct {
-	UserId int `json:"id"`
-}
-
 type UserInput struct {
 	Name        string            `json:"name" validate:"required"`
 	Email       string            `json:"email" validate:"required,email"`
@@ -41,6 +26,10 @@ type UserInput struct {
 	PhoneNumber string            `json:"phone_number" validate:"required"`
 }
 
+type RoleInput struct {
+	Role string `json:"role" validate:"required"`
+}
+
 type UserUpdate struct {
 	Name        string            `json:"name" validate:"required"`
 	Location    *geojson.Geometry `json:"location" validate:""`
@@ -49,63 +38,46 @@ type UserUpdate struct {
 
 // GetAllUsers godoc
 // @Summary Get all users
-// @Description Retrieves a list of all users with their locations in GeoJSON format
+// @Description Retrieves a list of all users with their locations in GeoJSON format. Email, phone number and role are only shown to the user themselves and to admins.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Success 200 {array} models.UserResponse "A list of users"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /users [get]
-func GetAllUsers(w http.ResponseWriter, r *http.Request) {
-	var result []struct {
-		models.User
-		LocationText string `json:"location"`
-	}
-
-	err := models.DB.Raw(`
-        SELECT users.*, ST_AsGeoJSON(users.location::geometry) AS location_text
-        FROM users`).Scan(&result).Error
-
+func (s *Server) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.repos.Users.List(r.Context())
 	if err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 
-	users := make([]models.User, len(result))
-	for i, r := range result {
-		if r.User.LocationEWKB == nil {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-		} else {
-			r.User.LocationText = common.GeoJSONText{Data: json.RawMessage(r.LocationText)}
-		}
-		users[i] = r.User
+	views := make([]interface{}, len(users))
+	for i, user := range users {
+		views[i] = userView(r, user)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(users); err != nil {
+	if err := json.NewEncoder(w).Encode(views); err != nil {
 		log.Printf("Request error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
 		return
 	}
 }
 
-func GetUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	var result struct {
-		models.User
-		LocationText string `json:"location"`
+// GetUser returns the user as userView shows them to the caller.
+func (s *Server) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "User is not found")
+		return
 	}
 
-	err := models.DB.Raw(`
-        SELECT users.*, ST_AsGeoJSON(users.location::geometry) AS location_text
-        FROM users WHERE users.id = ?`, id).Scan(&result).Error
-
+	user, err := s.repos.Users.Get(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "User is not found")
 		} else {
 			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
@@ -113,15 +85,9 @@ func GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if result.User.LocationEWKB == nil {
-		result.User.LocationText = common.GeoJSONText{Data: json.RawMessage("{}")}
-	} else {
-		result.User.LocationText = common.GeoJSONText{Data: json.RawMessage(result.LocationText)}
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(result.User); err != nil {
+	if err := json.NewEncoder(w).Encode(userView(r, user)); err != nil {
 		log.Printf("Serialization error: %v", err)
 		utils.RespondWithError(w, http.StatusInternalServerError, "Error encoding response")
 	}
@@ -143,17 +109,17 @@ func orbToEWKB(geom orb.Geometry, srid int) ([]byte, error) {
 // @Accept json
 // @Produce json
 // @Param user body models.UserInputS true "User data for registration"
-// @Success 200 {object} map[string]interface{} "id, token" "ID and token of the newly registered user"
+// @Success 200 {object} TokenPair "ID and tokens of the newly registered user"
 // @Failure 400 {object} string "Validation Error"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /users/registration [post]
-func RegisterUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	var userInput UserInput
 
 	body, _ := io.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &userInput)
 
-	validate = validator.New()
+	validate := validator.New()
 
 	err := validate.Struct(userInput)
 	if err != nil {
@@ -171,6 +137,7 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		locationEWKB, err = orbToEWKB(geom, 4326)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, "Location Validation Error")
+			return
 		}
 	}
 
@@ -185,36 +152,50 @@ func RegisterUser(w http.ResponseWriter, r *http.Request) {
 		Pass_hash:    hashedPassword,
 		LocationEWKB: locationEWKB,
 		PhoneNumber:  userInput.PhoneNumber,
+		Role:         models.RoleUser,
 	}
 
-	if err := models.DB.Create(user).Error; err != nil {
+	if err := s.repos.Users.Create(r.Context(), user); err != nil {
 		utils.RespondWithError(w, http.StatusForbidden, "Failed to create new user")
 		return
 	}
 
-	token, _ := GenerateToken()
+	created, err := s.repos.Users.Get(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.auditAs(r, &user.ID, models.EntityUser, user.ID, models.AuditCreate, nil, created)
+
+	tokens, err := s.issueTokens(r.Context(), *user)
+	if err != nil {
+		log.Printf("Error issuing tokens: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{"id": user.ID, "token": token})
+	json.NewEncoder(w).Encode(tokens)
 }
 
 // AuthenticateUser godoc
 // @Summary Authenticate a user
-// @Description Authenticates a user and returns a token
+// @Description Authenticates a user and returns an access token and a refresh token
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param credentials body models.AuthInputS true "User credentials for authentication"
-// @Success 200 {object} map[string]interface{} "id, token" "ID and token of the authenticated user"
+// @Success 200 {object} TokenPair "ID and tokens of the authenticated user"
 // @Failure 400 {object} string "Incorrect password or validation error"
 // @Failure 404 {object} string "User not found"
 // @Failure 500 {object} string "Internal Server Error"
 // @Router /users/authentication [post]
-func AuthenticateUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 	type AuthInput struct {
-		Email    string `json:email`
-		Password string `json:password`
+		Email    string `json:"email"`
+		Password string `json:"password"`
 	}
 
 	var authInput AuthInput
@@ -222,7 +203,7 @@ func AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 	body, _ := io.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &authInput)
 
-	validate = validator.New()
+	validate := validator.New()
 
 	err := validate.Struct(authInput)
 	if err != nil {
@@ -230,10 +211,9 @@ func AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	err = models.DB.Where("email = ?", authInput.Email).First(&user).Error
+	user, err := s.repos.Users.GetByEmail(r.Context(), authInput.Email)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "User not found")
 		} else {
 			log.Printf("Request error: %v", err)
@@ -247,35 +227,42 @@ func AuthenticateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, _ := GenerateToken()
+	tokens, err := s.issueTokens(r.Context(), user)
+	if err != nil {
+		log.Printf("Error issuing tokens: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{"id": user.ID, "token": token})
+	json.NewEncoder(w).Encode(tokens)
 }
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Deletes a user by ID
+// @Description Soft-deletes a user by ID together with their ads and signs them out everywhere. An admin can restore both.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 204 "User successfully deleted"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot delete another user"
 // @Failure 404 {object} string "User not found"
 // @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
 // @Router /users/{id} [delete]
-func DeleteUser(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	id := vars["id"]
-
-	var exists struct {
-		ID int `gorm:"column:id"`
+func (s *Server) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot delete another user")
+		return
 	}
-	err := models.DB.Raw(`SELECT id FROM users WHERE id = ?`, id).Scan(&exists).Error
 
+	user, err := s.repos.Users.Get(r.Context(), id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
+		if errors.Is(err, repository.ErrNotFound) {
 			utils.RespondWithError(w, http.StatusNotFound, "User not found")
 		} else {
 			log.Printf("Request error: %v", err)
@@ -284,16 +271,87 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = models.DB.Exec(`DELETE FROM users WHERE id = ?`, id).Error
+	deleted, err := s.repos.Users.Delete(r.Context(), id)
 	if err != nil {
-		log.Printf("Error deleting user: %v", err)
-		utils.RespondWithError(w, http.StatusInternalServerError, "Error deleting user")
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		} else {
+			log.Printf("Error deleting user: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Error deleting user")
+		}
 		return
 	}
+	s.audit(r, models.EntityUser, id, models.AuditDelete, user, nil)
+	s.auditDeleted(r, deleted)
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Brings back a deleted user with the ads that were deleted with them
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 "User restored"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "Deleted user not found"
+// @Failure 409 {object} string "Another user has taken the email or phone number"
+// @Failure 500 {object} string "Internal Server Error"
+// @Security BearerAuth
+// @Router /users/{id}/restore [post]
+func (s *Server) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+		return
+	}
+
+	if err := s.repos.Users.Restore(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			utils.RespondWithError(w, http.StatusNotFound, "Deleted user not found")
+		case errors.Is(err, repository.ErrDuplicate):
+			utils.RespondWithError(w, http.StatusConflict, "Another user has taken the email or phone number")
+		default:
+			log.Printf("Error restoring user: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	user, err := s.repos.Users.Get(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityUser, id, models.AuditRestore, nil, user)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func isSelf(r *http.Request, id uint) bool {
+	userID, ok := UserIDFromContext(r.Context())
+	return ok && userID == id
+}
+
+// userView shows the full user to the user themselves and to admins.
+// Everyone else gets the public profile ads carry, without the contact
+// details and the role.
+func userView(r *http.Request, user models.User) interface{} {
+	role, _ := RoleFromContext(r.Context())
+	if isSelf(r, user.ID) || role == models.RoleAdmin {
+		return user
+	}
+	return map[string]interface{}{
+		"id":       user.ID,
+		"name":     user.Name,
+		"location": user.LocationText,
+	}
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
 	return string(bytes), err
@@ -304,11 +362,6 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateToken() (string, error) {
-	token := jwt.New(jwt.SigningMethodHS256)
-	return token.SignedString([]byte(signingKey))
-}
-
 // UpdateUser godoc
 // @Summary Update user details
 // @Description Updates details of an existing user by ID.
@@ -319,28 +372,36 @@ func GenerateToken() (string, error) {
 // @Param user body models.UserUpdateSwagger true "User data to update"
 // @Success 200 {object} models.UserResponse "Successfully updated user details"
 // @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Cannot update another user"
 // @Failure 404 {object} string "User not found"
+// @Security BearerAuth
 // @Router /users/{id} [put]
-func UpdateUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	var (
 		locationEWKB []byte
 		geom         orb.Geometry
 		input        UserUpdate
-		user         models.User
 	)
 
-	id := mux.Vars(r)["id"]
+	id, ok := pathID(r, "id")
+	if !ok || !isSelf(r, id) {
+		utils.RespondWithError(w, http.StatusForbidden, "Cannot update another user")
+		return
+	}
 
-	if err := models.DB.Where("id = ?", id).First(&user).Error; err != nil {
+	user, err := s.repos.Users.Get(r.Context(), id)
+	if err != nil {
 		utils.RespondWithError(w, http.StatusNotFound, "User not found")
 		return
 	}
+	before := user
 
 	body, _ := io.ReadAll(r.Body)
 	_ = json.Unmarshal(body, &input)
 
 	validate := validator.New()
-	err := validate.Struct(input)
+	err = validate.Struct(input)
 
 	if err != nil {
 		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
@@ -352,6 +413,7 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 		locationEWKB, err = orbToEWKB(geom, 4326)
 		if err != nil {
 			utils.RespondWithError(w, http.StatusBadRequest, "Location Validation Error")
+			return
 		}
 	}
 
@@ -359,7 +421,86 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	user.LocationEWKB = locationEWKB
 	user.PhoneNumber = input.PhoneNumber
 
-	models.DB.Save(&user)
+	if err := s.repos.Users.Update(r.Context(), &user); err != nil {
+		log.Printf("Error updating user: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// Reload to render the new location as GeoJSON.
+	if user, err = s.repos.Users.Get(r.Context(), id); err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityUser, id, models.AuditUpdate, before, user)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// UpdateUserRole godoc
+// @Summary Change a user's role
+// @Description Promotes or demotes a user. Available to admins only.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body RoleInput true "New role: user, moderator or admin"
+// @Success 200 {object} models.UserResponse "User with the updated role"
+// @Failure 400 {object} string "Validation Error"
+// @Failure 401 {object} string "Unauthorized"
+// @Failure 403 {object} string "Insufficient permissions"
+// @Failure 404 {object} string "User not found"
+// @Security BearerAuth
+// @Router /users/{id}/role [put]
+func (s *Server) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var input RoleInput
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := validator.New().Struct(input); err != nil || !models.IsValidRole(input.Role) {
+		utils.RespondWithError(w, http.StatusBadRequest, "Validation Error")
+		return
+	}
+
+	id, ok := pathID(r, "id")
+	if !ok {
+		utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	before, err := s.repos.Users.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		} else {
+			log.Printf("Request error: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	if err := s.repos.Users.UpdateRole(r.Context(), id, input.Role); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			utils.RespondWithError(w, http.StatusNotFound, "User not found")
+		} else {
+			log.Printf("Error updating user role: %v", err)
+			utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		}
+		return
+	}
+
+	user, err := s.repos.Users.Get(r.Context(), id)
+	if err != nil {
+		log.Printf("Request error: %v", err)
+		utils.RespondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	s.audit(r, models.EntityUser, id, models.AuditUpdate, before, user)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(user)
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+func TestUserContactsAreHidden(t *testing.T) {
+	ts := newTestServer(t)
+	sellerID, seller := ts.user("seller", models.RoleUser)
+	otherID, other := ts.user("other", models.RoleUser)
+	adminID, admin := ts.user("admin", models.RoleAdmin)
+	path := fmt.Sprintf("/users/%d", sellerID)
+
+	tests := []struct {
+		name     string
+		callerID uint
+		token    string
+		contacts bool
+	}{
+		{"anonymous", 0, "", false},
+		{"other user", otherID, other, false},
+		{"self", sellerID, seller, true},
+		{"admin", adminID, admin, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := ts.do("GET", path, test.token, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var user map[string]interface{}
+			decode(t, rec, &user)
+			if user["name"] != "seller" {
+				t.Errorf("user %v", user)
+			}
+			for _, field := range []string{"email", "phone_number", "role"} {
+				if _, ok := user[field]; ok != test.contacts {
+					t.Errorf("%s shown: %v, want %v", field, ok, test.contacts)
+				}
+			}
+
+			rec = ts.do("GET", "/users", test.token, nil)
+			expectStatus(t, rec, http.StatusOK)
+			var users []map[string]interface{}
+			decode(t, rec, &users)
+			for _, user := range users {
+				_, ok := user["email"]
+				if want := test.callerID == adminID || user["id"] == float64(test.callerID); ok != want {
+					t.Errorf("GET /users shows the email of %v: %v, want %v", user["name"], ok, want)
+				}
+			}
+		})
+	}
+}
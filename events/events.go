@@ -0,0 +1,33 @@
+// Package events pushes notifications to connected users. Memory fans
+// them out within the process.
+package events
+
+// Event types.
+const (
+	// MessageCreated carries a models.Message posted to a conversation
+	// the user takes part in.
+	MessageCreated = "message.created"
+	// AdUpdated and AdDeleted carry the ID of an ad the user has
+	// bookmarked.
+	AdUpdated = "ad.updated"
+	AdDeleted = "ad.deleted"
+	// AdMatched carries a models.Notification of a new ad that matches
+	// one of the user's saved searches.
+	AdMatched = "ad.matched"
+)
+
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Broker delivers events to the subscriptions of individual users.
+type Broker interface {
+	// Publish delivers the event to every subscription of the given
+	// users. It does not block: a subscriber that falls behind misses
+	// events.
+	Publish(event Event, userIDs ...uint)
+	// Subscribe returns a channel that receives the events published to
+	// the user until cancel is called.
+	Subscribe(userID uint) (events <-chan Event, cancel func())
+}
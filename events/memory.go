@@ -0,0 +1,60 @@
+package events
+
+import (
+	"sync"
+)
+
+// bufferSize is the number of events a subscription holds before further
+// events to it are dropped.
+const bufferSize = 16
+
+// Memory is a Broker for a single server process.
+type Memory struct {
+	mu   sync.RWMutex
+	subs map[uint]map[chan Event]struct{}
+}
+
+func NewMemory() *Memory {
+	return &Memory{subs: make(map[uint]map[chan Event]struct{})}
+}
+
+func (m *Memory) Publish(event Event, userIDs ...uint) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, userID := range userIDs {
+		for ch := range m.subs[userID] {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+func (m *Memory) Subscribe(userID uint) (<-chan Event, func()) {
+	ch := make(chan Event, bufferSize)
+
+	m.mu.Lock()
+	if m.subs[userID] == nil {
+		m.subs[userID] = make(map[chan Event]struct{})
+	}
+	m.subs[userID][ch] = struct{}{}
+	m.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			delete(m.subs[userID], ch)
+			if len(m.subs[userID]) == 0 {
+				delete(m.subs, userID)
+			}
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
@@ -0,0 +1,94 @@
+// Package expiry takes ads down once their time to live has passed.
+package expiry
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+// Interval is how often Run looks for expired ads.
+const Interval = time.Minute
+
+// Clock tells the current time. Tests substitute their own.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is the wall clock.
+var SystemClock Clock = systemClock{}
+
+// Expirer marks active ads whose expiry has passed as expired, records
+// the change in the audit log and tells the users who bookmarked them.
+type Expirer struct {
+	repos  repository.Repositories
+	broker events.Broker
+	clock  Clock
+}
+
+func NewExpirer(repos repository.Repositories, broker events.Broker, clock Clock) *Expirer {
+	return &Expirer{repos: repos, broker: broker, clock: clock}
+}
+
+// Run expires ads every interval until ctx is canceled.
+func (e *Expirer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.ExpireDue(ctx); err != nil {
+			log.Printf("Error expiring ads: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ExpireDue expires the ads that are due by the clock's current time and
+// returns their IDs.
+func (e *Expirer) ExpireDue(ctx context.Context) ([]uint, error) {
+	ids, err := e.repos.Ads.Expire(ctx, e.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		// Nobody makes the change and there is no request behind it, so
+		// the entry has neither an actor nor a request ID.
+		entry := models.AuditEntry{
+			Entity:   models.EntityAd,
+			EntityID: id,
+			Action:   models.AuditUpdate,
+			Changes: models.AuditChanges{
+				"status": {Before: models.StatusActive, After: models.StatusExpired},
+			},
+		}
+		if err := e.repos.Audit.Record(ctx, &entry); err != nil {
+			log.Printf("Error recording audit entry: %v", err)
+		}
+
+		users, err := e.repos.Favorites.Users(ctx, id)
+		if err != nil {
+			log.Printf("Error loading users to notify: %v", err)
+			continue
+		}
+		e.broker.Publish(events.Event{
+			Type: events.AdUpdated,
+			Data: map[string]interface{}{"ad_id": id},
+		}, users...)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,129 @@
+package expiry
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"github.com/sciphilib/go-dacha/repository/memory"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func TestExpireDue(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.New()
+	broker := events.NewMemory()
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	expirer := NewExpirer(repos, broker, clock)
+
+	seller := models.User{Name: "seller", Email: "seller@example.com", PhoneNumber: "+1"}
+	buyer := models.User{Name: "buyer", Email: "buyer@example.com", PhoneNumber: "+2"}
+	for _, user := range []*models.User{&seller, &buyer} {
+		if err := repos.Users.Create(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	category := models.Category{Name: "Garden"}
+	if err := repos.Categories.Create(ctx, &category); err != nil {
+		t.Fatal(err)
+	}
+	subcategory := models.Subcategory{Name: "Tools", CategoryID: category.ID}
+	if err := repos.Subcategories.Create(ctx, &subcategory); err != nil {
+		t.Fatal(err)
+	}
+
+	ad := func(status string, expiresAt time.Time) uint {
+		ad := models.Advertisement{
+			Title:          status,
+			Subcategory_id: subcategory.ID,
+			User_id:        seller.ID,
+			Status:         status,
+			ExpiresAt:      expiresAt,
+		}
+		if err := repos.Ads.Create(ctx, &ad); err != nil {
+			t.Fatal(err)
+		}
+		return ad.ID
+	}
+	soon := ad(models.StatusActive, start.Add(time.Hour))
+	later := ad(models.StatusActive, start.Add(2*time.Hour))
+	draft := ad(models.StatusDraft, start.Add(time.Hour))
+	reserved := ad(models.StatusReserved, start.Add(time.Hour))
+
+	if _, err := repos.Favorites.Add(ctx, buyer.ID, soon); err != nil {
+		t.Fatal(err)
+	}
+	stream, cancel := broker.Subscribe(buyer.ID)
+	defer cancel()
+
+	steps := []struct {
+		advance time.Duration
+		want    []uint
+	}{
+		{0, nil},
+		{time.Hour - time.Second, nil},
+		{time.Second, []uint{soon}},
+		{time.Minute, nil},
+		{2 * time.Hour, []uint{later}},
+	}
+	for _, step := range steps {
+		clock.now = clock.now.Add(step.advance)
+		got, err := expirer.ExpireDue(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, step.want) {
+			t.Fatalf("at %v expired %v, want %v", clock.now.Sub(start), got, step.want)
+		}
+	}
+
+	for id, want := range map[uint]string{
+		soon:     models.StatusExpired,
+		later:    models.StatusExpired,
+		draft:    models.StatusDraft,
+		reserved: models.StatusReserved,
+	} {
+		ad, err := repos.Ads.Get(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ad.Status != want {
+			t.Errorf("ad %q has status %q, want %q", ad.Title, ad.Status, want)
+		}
+	}
+
+	page, err := repos.Audit.List(ctx, repository.AuditFilter{Entity: models.EntityAd, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var audited []uint
+	for _, entry := range page.Entries {
+		if entry.ActorID != nil || entry.Changes["status"].After != models.StatusExpired {
+			t.Errorf("audit entry %+v", entry)
+		}
+		audited = append(audited, entry.EntityID)
+	}
+	slices.Sort(audited)
+	if !slices.Equal(audited, []uint{soon, later}) {
+		t.Errorf("audit log records ads %v, want %v", audited, []uint{soon, later})
+	}
+
+	select {
+	case event := <-stream:
+		if event.Type != events.AdUpdated {
+			t.Errorf("event %q, want %q", event.Type, events.AdUpdated)
+		}
+	default:
+		t.Error("the user who bookmarked the expired ad was not notified")
+	}
+}
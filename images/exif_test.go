@@ -0,0 +1,178 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"slices"
+	"testing"
+)
+
+// tiff builds a TIFF structure whose first IFD holds the given entries
+// in tag order, each with a SHORT value stored inline. extra is appended
+// after the IFD.
+func tiff(order binary.ByteOrder, entries map[uint16]uint16, extra []byte) []byte {
+	var buf bytes.Buffer
+	if order == binary.LittleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	binary.Write(&buf, order, uint16(42))
+	binary.Write(&buf, order, uint32(8))
+
+	tags := make([]uint16, 0, len(entries))
+	for tag := range entries {
+		tags = append(tags, tag)
+	}
+	slices.Sort(tags)
+
+	binary.Write(&buf, order, uint16(len(entries)))
+	for _, tag := range tags {
+		value := entries[tag]
+		binary.Write(&buf, order, tag)
+		binary.Write(&buf, order, uint16(3)) // SHORT
+		binary.Write(&buf, order, uint32(1))
+		binary.Write(&buf, order, value)
+		binary.Write(&buf, order, uint16(0))
+	}
+	binary.Write(&buf, order, uint32(0))
+	buf.Write(extra)
+	return buf.Bytes()
+}
+
+// segment encodes a JPEG marker segment with the payload.
+func segment(marker byte, payload []byte) []byte {
+	seg := []byte{0xFF, marker, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	return append(seg, payload...)
+}
+
+func exif(tiff []byte) []byte {
+	return segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))
+}
+
+// fixture encodes a w x h JPEG and inserts the segments right after its
+// start of image marker.
+func fixture(t *testing.T, w, h int, segments ...[]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	result := append([]byte{}, data[:2]...)
+	for _, s := range segments {
+		result = append(result, s...)
+	}
+	return append(result, data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	orientation := func(order binary.ByteOrder, value uint16) []byte {
+		return exif(tiff(order, map[uint16]uint16{orientationTag: value}, nil))
+	}
+
+	tests := []struct {
+		name     string
+		segments [][]byte
+		want     int
+	}{
+		{"no EXIF", nil, 1},
+		{"little endian", [][]byte{orientation(binary.LittleEndian, 6)}, 6},
+		{"big endian", [][]byte{orientation(binary.BigEndian, 8)}, 8},
+		{"after other segments", [][]byte{segment(0xE0, []byte("JFIF\x00\x01\x02")), orientation(binary.LittleEndian, 3)}, 3},
+		{"other tags first", [][]byte{exif(tiff(binary.LittleEndian, map[uint16]uint16{0x0100: 640, orientationTag: 5}, nil))}, 5},
+		{"no orientation tag", [][]byte{exif(tiff(binary.LittleEndian, map[uint16]uint16{0x0100: 640}, nil))}, 1},
+		{"orientation out of range", [][]byte{orientation(binary.LittleEndian, 9)}, 1},
+		{"orientation zero", [][]byte{orientation(binary.LittleEndian, 0)}, 1},
+		{"APP1 that is not EXIF", [][]byte{segment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00"))}, 1},
+		{"bad byte order", [][]byte{exif(append([]byte("XX"), tiff(binary.LittleEndian, map[uint16]uint16{orientationTag: 6}, nil)[2:]...))}, 1},
+		{"bad magic number", [][]byte{exif(append([]byte("II\x2b\x00"), tiff(binary.LittleEndian, map[uint16]uint16{orientationTag: 6}, nil)[4:]...))}, 1},
+		{"truncated TIFF header", [][]byte{exif([]byte("II\x2a\x00"))}, 1},
+		{"IFD offset past the end", [][]byte{exif([]byte("II\x2a\x00\xff\xff\x00\x00"))}, 1},
+		{"IFD offset inside the header", [][]byte{exif([]byte("II\x2a\x00\x02\x00\x00\x00\x00\x00"))}, 1},
+		{"entry count past the end", [][]byte{exif([]byte("II\x2a\x00\x08\x00\x00\x00\xff\x00\x12\x01\x03\x00"))}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := jpegOrientation(fixture(t, 4, 4, test.segments...)); got != test.want {
+				t.Errorf("orientation %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestJPEGOrientationMalformed(t *testing.T) {
+	valid := fixture(t, 4, 4, exif(tiff(binary.LittleEndian, map[uint16]uint16{orientationTag: 6}, nil)))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a JPEG", []byte("\x89PNG\r\n\x1a\n")},
+		{"start of image only", []byte{0xFF, 0xD8}},
+		{"segment length past the end", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}},
+		{"segment length below two", []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xD9}},
+		{"garbage instead of a marker", []byte{0xFF, 0xD8, 0x00, 0x00, 0x00, 0x00}},
+	}
+	for i := 2; i < 40; i += 7 {
+		tests = append(tests, struct {
+			name string
+			data []byte
+		}{"truncated EXIF", valid[:i]})
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := jpegOrientation(test.data); got != 1 {
+				t.Errorf("orientation %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// The stored image is
+	//   A B C
+	//   D E F
+	// and each orientation lists the rows it displays as.
+	const A, B, C, D, E, F = 10, 20, 30, 40, 50, 60
+	src := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for i, v := range []uint8{A, B, C, D, E, F} {
+		src.SetNRGBA(i%3, i/3, color.NRGBA{R: v, A: 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        [][]uint8
+	}{
+		{1, [][]uint8{{A, B, C}, {D, E, F}}},
+		{2, [][]uint8{{C, B, A}, {F, E, D}}},
+		{3, [][]uint8{{F, E, D}, {C, B, A}}},
+		{4, [][]uint8{{D, E, F}, {A, B, C}}},
+		{5, [][]uint8{{A, D}, {B, E}, {C, F}}},
+		{6, [][]uint8{{D, A}, {E, B}, {F, C}}},
+		{7, [][]uint8{{F, C}, {E, B}, {D, A}}},
+		{8, [][]uint8{{C, F}, {B, E}, {A, D}}},
+	}
+	for _, test := range tests {
+		dst := orient(src, test.orientation)
+		if dst.Rect.Dx() != len(test.want[0]) || dst.Rect.Dy() != len(test.want) {
+			t.Errorf("orientation %d: size %v, want %dx%d", test.orientation, dst.Rect.Size(), len(test.want[0]), len(test.want))
+			continue
+		}
+		for y, row := range test.want {
+			for x, want := range row {
+				if got := dst.NRGBAAt(x, y).R; got != want {
+					t.Errorf("orientation %d: pixel (%d, %d) is %d, want %d", test.orientation, x, y, got, want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Package images prepares uploaded pictures for publishing. It applies the
+// EXIF orientation, re-encodes the picture so no metadata such as GPS
+// coordinates survives, and renders smaller variants for listings.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported image format")
+	ErrTooLarge    = errors.New("image dimensions too large")
+)
+
+// maxPixels bounds the decoded size so a small file cannot claim a huge
+// canvas and exhaust memory.
+const maxPixels = 50_000_000
+
+const (
+	originalQuality = 90
+	variantQuality  = 85
+)
+
+// Variant is a resized copy that fits in a MaxSize x MaxSize square.
+type Variant struct {
+	Name    string
+	MaxSize int
+}
+
+var (
+	Medium    = Variant{Name: "medium", MaxSize: 1024}
+	Thumbnail = Variant{Name: "thumb", MaxSize: 240}
+
+	Variants = []Variant{Medium, Thumbnail}
+)
+
+// Processed holds the encoded original and variants of a picture, all in
+// the format of the upload.
+type Processed struct {
+	// Extension is ".jpg" or ".png", including the dot.
+	Extension string
+	Original  []byte
+	Variants  map[string][]byte
+}
+
+// Process decodes a JPEG or PNG picture and encodes the original and every
+// variant.
+func Process(r io.Reader) (*Processed, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if format != "jpeg" && format != "png" {
+		return nil, ErrUnsupported
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	img := toNRGBA(decoded)
+	if format == "jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+
+	encode := func(img image.Image, quality int) ([]byte, error) {
+		var buf bytes.Buffer
+		var err error
+		if format == "jpeg" {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+		} else {
+			err = png.Encode(&buf, img)
+		}
+		return buf.Bytes(), err
+	}
+
+	processed := &Processed{
+		Extension: ".png",
+		Variants:  make(map[string][]byte, len(Variants)),
+	}
+	if format == "jpeg" {
+		processed.Extension = ".jpg"
+	}
+
+	if processed.Original, err = encode(img, originalQuality); err != nil {
+		return nil, err
+	}
+	for _, variant := range Variants {
+		if processed.Variants[variant.Name], err = encode(fit(img, variant.MaxSize), variantQuality); err != nil {
+			return nil, err
+		}
+	}
+
+	return processed, nil
+}
+
+// VariantKey names a variant after its original by adding the variant name
+// before the extension: "ads/1/ab12.jpg" becomes "ads/1/ab12_thumb.jpg".
+// It works on URLs as well as storage keys.
+func VariantKey(key, variant string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "_" + variant + ext
+}
+
+func toNRGBA(src image.Image) *image.NRGBA {
+	if img, ok := src.(*image.NRGBA); ok && img.Rect.Min == (image.Point{}) {
+		return img
+	}
+	bounds := src.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Rect, src, bounds.Min, draw.Src)
+	return img
+}
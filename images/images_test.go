@@ -0,0 +1,129 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestProcessOrientsAndStripsMetadata(t *testing.T) {
+	// 0x8825 points to the GPS IFD; the marker stands in for its data.
+	gps := []byte("GPS-COORDINATES-55.7558N-37.6173E")
+	for orientation := 1; orientation <= 8; orientation++ {
+		data := fixture(t, 40, 20, exif(tiff(binary.BigEndian, map[uint16]uint16{orientationTag: uint16(orientation), 0x8825: 38}, gps)))
+
+		processed, err := Process(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("orientation %d: %v", orientation, err)
+		}
+		if processed.Extension != ".jpg" {
+			t.Errorf("orientation %d: extension %q", orientation, processed.Extension)
+		}
+
+		wantW, wantH := 40, 20
+		if orientation >= 5 {
+			wantW, wantH = 20, 40
+		}
+		outputs := map[string][]byte{"original": processed.Original}
+		for name, variant := range processed.Variants {
+			outputs[name] = variant
+		}
+		for name, output := range outputs {
+			config, _, err := image.DecodeConfig(bytes.NewReader(output))
+			if err != nil {
+				t.Fatalf("orientation %d: decoding %s: %v", orientation, name, err)
+			}
+			if config.Width != wantW || config.Height != wantH {
+				t.Errorf("orientation %d: %s is %dx%d, want %dx%d", orientation, name, config.Width, config.Height, wantW, wantH)
+			}
+			if bytes.Contains(output, []byte("Exif")) || bytes.Contains(output, gps) {
+				t.Errorf("orientation %d: %s keeps EXIF metadata", orientation, name)
+			}
+		}
+	}
+}
+
+func TestProcessVariantSizes(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 2000, 500))); err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := Process(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.Extension != ".png" {
+		t.Errorf("extension %q, want .png", processed.Extension)
+	}
+
+	want := map[string]image.Point{
+		Medium.Name:    {1024, 256},
+		Thumbnail.Name: {240, 60},
+	}
+	for name, size := range want {
+		config, err := png.DecodeConfig(bytes.NewReader(processed.Variants[name]))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := (image.Point{config.Width, config.Height}); got != size {
+			t.Errorf("%s is %v, want %v", name, got, size)
+		}
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	var gif bytes.Buffer
+	gif.WriteString("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+
+	var huge bytes.Buffer
+	huge.Write([]byte("\x89PNG\r\n\x1a\n"))
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, 100_000)
+	binary.BigEndian.PutUint32(ihdr[4:], 100_000)
+	ihdr[8], ihdr[9] = 8, 6
+	binary.Write(&huge, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	huge.Write(chunk)
+	binary.Write(&huge, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"garbage", []byte("not an image"), ErrUnsupported},
+		{"GIF", gif.Bytes(), ErrUnsupported},
+		{"huge canvas", huge.Bytes(), ErrTooLarge},
+		{"truncated JPEG", fixture(t, 40, 20)[:100], ErrUnsupported},
+	}
+	for _, test := range tests {
+		if _, err := Process(bytes.NewReader(test.data)); err != test.want {
+			t.Errorf("%s: error %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestResizeAveragesOpaquePixels(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, color.NRGBA{R: 200, A: 255})
+	src.SetNRGBA(1, 0, color.NRGBA{G: 255, A: 0})
+
+	got := resize(src, 1, 1).NRGBAAt(0, 0)
+	if want := (color.NRGBA{R: 200, A: 127}); got != want {
+		t.Errorf("resized pixel %v, want %v", got, want)
+	}
+}
+
+func TestVariantKey(t *testing.T) {
+	if got := VariantKey("ads/1/ab12.jpg", Thumbnail.Name); got != "ads/1/ab12_thumb.jpg" {
+		t.Errorf("VariantKey = %q", got)
+	}
+	if got := VariantKey("/media/ads/1/ab12.png", Medium.Name); got != "/media/ads/1/ab12_medium.png" {
+		t.Errorf("VariantKey = %q", got)
+	}
+}
@@ -1,23 +1,75 @@
 package main
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+
 	"github.com/joho/godotenv"
+	"github.com/sciphilib/go-dacha/alerts"
 	"github.com/sciphilib/go-dacha/controllers"
+	"github.com/sciphilib/go-dacha/events"
+	"github.com/sciphilib/go-dacha/expiry"
 	"github.com/sciphilib/go-dacha/models"
-	"net/http"
+	"github.com/sciphilib/go-dacha/repository/postgres"
+	"github.com/sciphilib/go-dacha/storage"
 )
 
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token in the form "Bearer <token>"
 func main() {
 	godotenv.Load()
 
-	handler := controllers.New()
+	db := models.ConnectDatabase()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	config, err := controllers.LoadAuthConfig()
+	if err != nil {
+		log.Fatal("Failed to load auth config: ", err)
+	}
+
+	adConfig, err := controllers.LoadAdConfig()
+	if err != nil {
+		log.Fatal("Failed to load ad config: ", err)
+	}
+
+	files, err := storage.NewLocal(getenv("MEDIA_DIR", "media"), getenv("MEDIA_URL", "/media/"))
+	if err != nil {
+		log.Fatal("Failed to open media storage: ", err)
+	}
+
+	repos := postgres.New(db)
+	broker := events.NewMemory()
+
+	matcher := alerts.NewMatcher(repos, broker)
+	go matcher.Run(context.Background(), alerts.Interval)
+
+	expirer := expiry.NewExpirer(repos, broker, expiry.SystemClock)
+	go expirer.Run(context.Background(), expiry.Interval)
+
+	handler := controllers.New(repos, files, broker, matcher, config, adConfig)
 
 	server := &http.Server{
 		Addr:    "0.0.0.0:8008",
 		Handler: handler,
 	}
 
-	models.ConnectDatabase()
-
 	server.ListenAndServe()
 }
+
+// getenv returns the environment variable or fallback when it is unset.
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,45 @@
+CREATE EXTENSION IF NOT EXISTS postgis;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    email text NOT NULL,
+    pass_hash text NOT NULL,
+    location geography(Point, 4326),
+    phone_number text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_number_key ON users (phone_number);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name);
+
+CREATE TABLE IF NOT EXISTS subcategories (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL,
+    category_id bigint NOT NULL REFERENCES categories (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS subcategories_category_id_name_key ON subcategories (category_id, name);
+
+CREATE TABLE IF NOT EXISTS advertisements (
+    id bigserial PRIMARY KEY,
+    title text NOT NULL,
+    price text NOT NULL,
+    subcategory_id bigint NOT NULL REFERENCES subcategories (id),
+    description text NOT NULL DEFAULT '',
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    datetime timestamptz NOT NULL,
+    pictures text[],
+    location geography(Point, 4326)
+);
+
+CREATE INDEX IF NOT EXISTS advertisements_subcategory_id_idx ON advertisements (subcategory_id);
+CREATE INDEX IF NOT EXISTS advertisements_user_id_idx ON advertisements (user_id);
+CREATE INDEX IF NOT EXISTS advertisements_datetime_idx ON advertisements (datetime);
+CREATE INDEX IF NOT EXISTS advertisements_location_idx ON advertisements USING GIST (location);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS advertisements_search_vector_idx;
+ALTER TABLE advertisements DROP COLUMN IF EXISTS search_vector;
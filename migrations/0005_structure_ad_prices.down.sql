@@ -0,0 +1,17 @@
+ALTER TABLE advertisements ADD COLUMN price text NOT NULL DEFAULT '';
+
+UPDATE advertisements SET price = CASE
+    WHEN price_free THEN 'бесплатно'
+    ELSE trim(to_char(price_amount / 100.0, 'FM999999999990.99'), '.') || ' ' || price_currency ||
+        CASE WHEN price_negotiable THEN ', торг' ELSE '' END
+END;
+
+ALTER TABLE advertisements ALTER COLUMN price DROP DEFAULT;
+
+DROP INDEX IF EXISTS advertisements_price_idx;
+
+ALTER TABLE advertisements
+    DROP COLUMN price_amount,
+    DROP COLUMN price_currency,
+    DROP COLUMN price_free,
+    DROP COLUMN price_negotiable;
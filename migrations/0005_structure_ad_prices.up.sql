@@ -0,0 +1,34 @@
+-- Splits the free-form price string into the structured price columns.
+-- Skipped when the price column is already gone.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'advertisements' AND column_name = 'price'
+    ) THEN
+        ALTER TABLE advertisements
+            ADD COLUMN IF NOT EXISTS price_amount bigint NOT NULL DEFAULT 0,
+            ADD COLUMN IF NOT EXISTS price_currency varchar(3) NOT NULL DEFAULT 'RUB',
+            ADD COLUMN IF NOT EXISTS price_free boolean NOT NULL DEFAULT false,
+            ADD COLUMN IF NOT EXISTS price_negotiable boolean NOT NULL DEFAULT false;
+
+        UPDATE advertisements SET
+            price_amount = coalesce(round(replace(regexp_replace(
+                substring(price from '[0-9][0-9\s]*(?:[.,][0-9]{1,2}(?![0-9]))?'),
+                '\s', '', 'g'), ',', '.')::numeric * 100), 0),
+            price_currency = CASE
+                WHEN price ~* '(\$|usd|долл)' THEN 'USD'
+                WHEN price ~* '(€|eur|евро)' THEN 'EUR'
+                ELSE 'RUB'
+            END,
+            price_free = price ~* '(бесплатно|даром|free)',
+            price_negotiable = price ~* '(договорн|торг|negotiable)';
+
+        UPDATE advertisements SET price_amount = 0, price_currency = '' WHERE price_free;
+
+        ALTER TABLE advertisements DROP COLUMN price;
+    END IF;
+END
+$$;
+
+CREATE INDEX IF NOT EXISTS advertisements_price_idx ON advertisements (price_currency, price_amount);
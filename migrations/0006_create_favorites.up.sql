@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS favorites (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ad_id bigint NOT NULL REFERENCES advertisements (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, ad_id)
+);
+
+CREATE INDEX IF NOT EXISTS favorites_ad_id_idx ON favorites (ad_id);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS conversations (
+    id bigserial PRIMARY KEY,
+    ad_id bigint NOT NULL REFERENCES advertisements (id) ON DELETE CASCADE,
+    buyer_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    seller_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    last_message_at timestamptz,
+    UNIQUE (ad_id, buyer_id)
+);
+
+CREATE INDEX IF NOT EXISTS conversations_buyer_id_idx ON conversations (buyer_id);
+CREATE INDEX IF NOT EXISTS conversations_seller_id_idx ON conversations (seller_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id bigserial PRIMARY KEY,
+    conversation_id bigint NOT NULL REFERENCES conversations (id) ON DELETE CASCADE,
+    sender_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    body text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    read_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS messages_conversation_id_idx ON messages (conversation_id, id);
+CREATE INDEX IF NOT EXISTS messages_unread_idx ON messages (conversation_id) WHERE read_at IS NULL;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS advertisements_unmatched_idx;
+ALTER TABLE advertisements DROP COLUMN IF EXISTS matched_at;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS saved_searches;
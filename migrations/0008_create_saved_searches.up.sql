@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name text NOT NULL,
+    category_id bigint REFERENCES categories (id) ON DELETE CASCADE,
+    subcategory_id bigint REFERENCES subcategories (id) ON DELETE CASCADE,
+    price_min bigint,
+    price_max bigint,
+    currency char(3) NOT NULL DEFAULT '',
+    query text NOT NULL DEFAULT '',
+    lat double precision,
+    lon double precision,
+    radius double precision,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    CHECK ((lat IS NULL) = (lon IS NULL) AND (lat IS NULL) = (radius IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS saved_searches_user_id_idx ON saved_searches (user_id);
+CREATE INDEX IF NOT EXISTS saved_searches_category_id_idx ON saved_searches (category_id);
+CREATE INDEX IF NOT EXISTS saved_searches_subcategory_id_idx ON saved_searches (subcategory_id);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    ad_id bigint NOT NULL REFERENCES advertisements (id) ON DELETE CASCADE,
+    saved_search_id bigint NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    read_at timestamptz,
+    UNIQUE (saved_search_id, ad_id)
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, id);
+
+-- Ads with no matched_at have not been matched against the saved searches
+-- yet; the matcher sets it once the notifications are stored. Existing ads
+-- count as matched.
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS matched_at timestamptz;
+UPDATE advertisements SET matched_at = now() WHERE matched_at IS NULL;
+CREATE INDEX IF NOT EXISTS advertisements_unmatched_idx ON advertisements (id) WHERE matched_at IS NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS status varchar(16) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'expired'));
+
+CREATE INDEX IF NOT EXISTS advertisements_status_idx ON advertisements (status);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS advertisements_active_expires_at_idx;
+ALTER TABLE advertisements DROP COLUMN IF EXISTS expires_at;
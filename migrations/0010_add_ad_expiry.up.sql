@@ -0,0 +1,8 @@
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS expires_at timestamptz;
+
+-- Existing ads get the default AD_TTL of 30 days from their datetime.
+UPDATE advertisements SET expires_at = datetime + interval '30 days' WHERE expires_at IS NULL;
+
+ALTER TABLE advertisements ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS advertisements_active_expires_at_idx ON advertisements (expires_at) WHERE status = 'active';
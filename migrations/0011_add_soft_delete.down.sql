@@ -0,0 +1,20 @@
+-- Rows deleted while soft delete was in place are purged.
+DELETE FROM advertisements WHERE deleted_at IS NOT NULL;
+DELETE FROM subcategories WHERE deleted_at IS NOT NULL;
+DELETE FROM categories WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_phone_number_key;
+DROP INDEX IF EXISTS categories_name_key;
+DROP INDEX IF EXISTS subcategories_category_id_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_number_key ON users (phone_number);
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name);
+CREATE UNIQUE INDEX IF NOT EXISTS subcategories_category_id_name_key ON subcategories (category_id, name);
+
+ALTER TABLE advertisements DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE subcategories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,21 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE subcategories ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_deleted_at_idx ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS categories_deleted_at_idx ON categories (deleted_at);
+CREATE INDEX IF NOT EXISTS subcategories_deleted_at_idx ON subcategories (deleted_at);
+CREATE INDEX IF NOT EXISTS advertisements_deleted_at_idx ON advertisements (deleted_at);
+
+-- Deleted rows keep their unique keys only until a live row takes them,
+-- so the unique indexes cover live rows only.
+DROP INDEX IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_phone_number_key;
+DROP INDEX IF EXISTS categories_name_key;
+DROP INDEX IF EXISTS subcategories_category_id_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_phone_number_key ON users (phone_number) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS subcategories_category_id_name_key ON subcategories (category_id, name) WHERE deleted_at IS NULL;
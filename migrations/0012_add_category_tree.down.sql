@@ -0,0 +1,10 @@
+-- Nested categories become top-level ones, so this fails while two live
+-- categories share a name.
+DROP INDEX IF EXISTS categories_parent_id_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_key ON categories (name) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS categories_path_idx;
+DROP INDEX IF EXISTS categories_parent_id_idx;
+
+ALTER TABLE categories DROP COLUMN IF EXISTS path;
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_id;
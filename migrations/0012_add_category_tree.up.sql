@@ -0,0 +1,16 @@
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES categories (id);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS path text;
+
+UPDATE categories SET path = id || '/' WHERE path IS NULL;
+
+ALTER TABLE categories ALTER COLUMN path SET NOT NULL;
+ALTER TABLE categories ALTER COLUMN path SET DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+CREATE INDEX IF NOT EXISTS categories_path_idx ON categories (path text_pattern_ops);
+
+-- Names are unique among the live children of each parent; roots count as
+-- children of parent 0.
+DROP INDEX IF EXISTS categories_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_parent_id_name_key
+    ON categories (COALESCE(parent_id, 0), name) WHERE deleted_at IS NULL;
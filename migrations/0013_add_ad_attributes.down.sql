@@ -0,0 +1,2 @@
+ALTER TABLE advertisements DROP COLUMN IF EXISTS attributes;
+ALTER TABLE subcategories DROP COLUMN IF EXISTS attribute_schema;
@@ -0,0 +1,2 @@
+ALTER TABLE subcategories ADD COLUMN IF NOT EXISTS attribute_schema jsonb NOT NULL DEFAULT '[]';
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS attributes jsonb NOT NULL DEFAULT '{}';
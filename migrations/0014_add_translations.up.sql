@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS translations (
+    entity text NOT NULL,
+    entity_id bigint NOT NULL,
+    locale text NOT NULL,
+    name text NOT NULL,
+    PRIMARY KEY (entity, entity_id, locale)
+);
+
+CREATE INDEX IF NOT EXISTS translations_entity_locale_idx ON translations (entity, locale);
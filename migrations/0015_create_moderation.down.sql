@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS moderation_actions;
+
+ALTER TABLE advertisements DROP COLUMN IF EXISTS reviewed_at;
+
+UPDATE advertisements SET status = 'draft' WHERE status IN ('rejected', 'hidden', 'pending');
+ALTER TABLE advertisements DROP CONSTRAINT IF EXISTS advertisements_status_check;
+ALTER TABLE advertisements ADD CONSTRAINT advertisements_status_check
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'expired'));
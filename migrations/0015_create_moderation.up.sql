@@ -0,0 +1,35 @@
+ALTER TABLE advertisements DROP CONSTRAINT IF EXISTS advertisements_status_check;
+ALTER TABLE advertisements ADD CONSTRAINT advertisements_status_check
+    CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'expired', 'rejected', 'hidden', 'pending'));
+
+-- Ads listed before moderation existed are not queued for review.
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS reviewed_at timestamptz;
+UPDATE advertisements SET reviewed_at = now() WHERE reviewed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id bigserial PRIMARY KEY,
+    ad_id bigint NOT NULL REFERENCES advertisements (id) ON DELETE CASCADE,
+    moderator_id bigint NOT NULL REFERENCES users (id),
+    action varchar(16) NOT NULL CHECK (action IN ('approve', 'reject', 'hide')),
+    reason text NOT NULL DEFAULT '',
+    from_status varchar(16) NOT NULL,
+    to_status varchar(16) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS moderation_actions_ad_id_idx ON moderation_actions (ad_id);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id bigserial PRIMARY KEY,
+    ad_id bigint NOT NULL REFERENCES advertisements (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    reason varchar(32) NOT NULL
+        CHECK (reason IN ('scam', 'prohibited', 'spam', 'duplicate', 'wrong_category', 'offensive', 'other')),
+    comment text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now(),
+    resolved_at timestamptz,
+    action_id bigint REFERENCES moderation_actions (id) ON DELETE SET NULL
+);
+
+-- A user has at most one open report per ad.
+CREATE UNIQUE INDEX IF NOT EXISTS reports_open_idx ON reports (ad_id, user_id) WHERE resolved_at IS NULL;
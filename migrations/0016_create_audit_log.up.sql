@@ -0,0 +1,17 @@
+-- Entries outlive the records and users they name, so nothing here is a
+-- foreign key.
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    entity varchar(32) NOT NULL,
+    entity_id bigint NOT NULL,
+    action varchar(16) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'restore')),
+    changes jsonb NOT NULL DEFAULT '{}',
+    request_id varchar(128) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_entries_entity_idx ON audit_entries (entity, entity_id);
+CREATE INDEX IF NOT EXISTS audit_entries_actor_id_idx ON audit_entries (actor_id);
+CREATE INDEX IF NOT EXISTS audit_entries_request_id_idx ON audit_entries (request_id);
+CREATE INDEX IF NOT EXISTS audit_entries_created_at_idx ON audit_entries (created_at);
@@ -1,16 +1,18 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/sciphilib/go-dacha/common"
+	"gorm.io/gorm"
 )
 
 type Advertisement struct {
 	ID             uint                    `gorm:"primaryKey;autoIncrement" json:"id"`
 	Title          string                  `json:"title"`
-	Price          string                  `json:"price"`
+	Price          Price                   `gorm:"embedded;embeddedPrefix:price_" json:"price"`
 	Subcategory_id uint                    `json:"subcategory_id"`
 	Subcategory    SubcategoryWithCategory `json:"-" gorm:"-"`
 	Description    string                  `json:"description"`
@@ -20,6 +22,94 @@ type Advertisement struct {
 	PicturesText   []string                `json:"-" gorm:"-"`
 	LocationText   common.GeoJSONText      `json:"location" gorm:"-"`
 	LocationEWKB   []byte                  `gorm:"column:location" json:"-"`
+	Status         string                  `json:"status" gorm:"default:active"`
+	ExpiresAt      time.Time               `json:"expires_at"`
+	Attributes     Attributes              `json:"attributes" gorm:"type:jsonb"`
+	ReviewedAt     *time.Time              `json:"-"`
+	MatchedAt      *time.Time              `json:"-"`
+	DeletedAt      gorm.DeletedAt          `json:"-"`
+}
+
+const (
+	StatusDraft    = "draft"
+	StatusActive   = "active"
+	StatusReserved = "reserved"
+	StatusSold     = "sold"
+	StatusExpired  = "expired"
+	// StatusRejected and StatusHidden are set by moderators. The owner
+	// can take a rejected ad back to draft to fix it; only a moderator
+	// brings back a hidden ad.
+	StatusRejected = "rejected"
+	StatusHidden   = "hidden"
+	// StatusPending is a rejected ad its owner published again. It stays
+	// unlisted until a moderator approves it.
+	StatusPending = "pending"
+)
+
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusDraft, StatusActive, StatusReserved, StatusSold, StatusExpired, StatusRejected, StatusHidden, StatusPending:
+		return true
+	}
+	return false
+}
+
+// PrivateStatuses are the statuses of ads that are only shown to their
+// owner and to moderators.
+var PrivateStatuses = []string{StatusDraft, StatusPending, StatusRejected, StatusHidden}
+
+// IsPrivateStatus tells whether the status is one of PrivateStatuses.
+func IsPrivateStatus(status string) bool {
+	return slices.Contains(PrivateStatuses, status)
+}
+
+// statusTransitions lists the statuses the owner can move an ad to from
+// each status. Ads only become expired when they time out and only become
+// active again when renewed. A pending ad becomes active only when a
+// moderator approves it.
+var statusTransitions = map[string][]string{
+	StatusDraft:    {StatusActive},
+	StatusActive:   {StatusDraft, StatusReserved, StatusSold},
+	StatusReserved: {StatusActive, StatusSold},
+	StatusSold:     {},
+	StatusExpired:  {StatusDraft},
+	StatusRejected: {StatusDraft},
+	StatusHidden:   {},
+	StatusPending:  {StatusDraft},
+}
+
+// CanChangeStatus reports whether the owner may move an ad from one
+// status to another.
+func CanChangeStatus(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// PublishedStatus returns the status a draft moves to when its owner
+// publishes it, given the last moderation action taken on the ad, if any.
+// A draft of a rejected ad goes to pending so that it is not listed again
+// without a moderator's approval.
+func PublishedStatus(lastAction string) string {
+	if lastAction == ActionReject {
+		return StatusPending
+	}
+	return StatusActive
+}
+
+// AdDetails is an advertisement together with its owner, as shown in
+// API responses. Distance is set only by location searches.
+type AdDetails struct {
+	Advertisement
+	User     User
+	Distance *float64
+}
+
+// Price is stored in minor units of the currency (kopecks, cents).
+// Amount and Currency are meaningless for free ads.
+type Price struct {
+	Amount     int64  `json:"amount" validate:"gte=0"`
+	Currency   string `json:"currency" validate:"omitempty,iso4217"`
+	Free       bool   `json:"free"`
+	Negotiable bool   `json:"negotiable"`
 }
 
 type SubcategoryWithCategory struct {
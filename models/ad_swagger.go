@@ -8,43 +8,81 @@ type AdAdded struct {
 	ID uint `json:"id"`
 }
 
+type AdStatus struct {
+	ID        uint      `json:"id"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // swagger:model AdInput
 type AdInput struct {
-	Title       string     `json:"title" validate:"required"`
-	Price       string     `json:"price" validate:"required"`
-	Subcategory string     `json:"subcategory" validate:"required"`
-	Category    string     `json:"category" validate:"required"`
-	Description string     `json:"description"`
-	UserEmail   string     `json:"user_email" validate:"required"`
-	Datetime    time.Time  `json:"datetime" validate:"required"`
-	Pictures    []string   `json:"pictures"`
-	Location    LocationAd `json:"location" validate:"required"`
+	Title         string     `json:"title" validate:"required"`
+	Price         Price      `json:"price" validate:"required"`
+	SubcategoryID uint       `json:"subcategory_id" validate:"required"`
+	Description   string     `json:"description"`
+	Datetime      time.Time  `json:"datetime" validate:"required"`
+	Location      LocationAd `json:"location" validate:"required"`
+	Status        string     `json:"status" enums:"draft,active"` // Только при создании, по умолчанию active
+	Attributes    Attributes `json:"attributes"`                  // Проверяются по attribute_schema подкатегории
 }
 
 // swagger:model AdResponse
 type AdResponse struct {
 	ID          uint          `json:"id"`
 	Title       string        `json:"title"`
-	Price       string        `json:"price"`
+	Price       Price         `json:"price"`
 	Description string        `json:"description"`
 	Subcategory SubcategoryAd `json:"subcategory"` // Предполагается, что Subcategory - это структура с полями id, name и category
-	User        UserAd        `json:"user"`        // Предполагается, что User - это структура с полями id, name, email, phone_number, и location
+	User        UserAd        `json:"user"`        // Предполагается, что User - это структура с полями id, name и location
 	Datetime    time.Time     `json:"datetime"`
-	Pictures    []string      `json:"pictures"`
-	Location    LocationAd    `json:"location"` // Предполагается, что Location - это структура с полями type и coordinates
+	Pictures    []Picture     `json:"pictures"`
+	Location    LocationAd    `json:"location"`              // Предполагается, что Location - это структура с полями type и coordinates
+	Distance    *float64      `json:"distance,omitempty"`    // Только в результатах /ads/search, в метрах
+	IsFavorite  *bool         `json:"is_favorite,omitempty"` // Только для запросов с токеном
+	Status      string        `json:"status"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	Attributes  Attributes    `json:"attributes"`
+}
+
+// AdPictures lists the pictures of an ad in display order.
+type AdPictures struct {
+	Pictures []Picture `json:"pictures"`
+}
+
+// Picture holds the URLs of an uploaded picture and its resized variants:
+// medium fits in 1024x1024 and thumbnail in 240x240 pixels.
+type Picture struct {
+	Original  string `json:"original"`
+	Medium    string `json:"medium"`
+	Thumbnail string `json:"thumbnail"`
+}
+
+// swagger:model AdPage
+type AdPage struct {
+	Items      []AdResponse `json:"items"`
+	Pagination Pagination   `json:"pagination"`
+}
+
+// Pagination describes where a page sits in the full result set.
+// NextCursor is set only when HasMore is true.
+type Pagination struct {
+	Limit      int   `json:"limit"`
+	Offset     int   `json:"offset"`
+	Total      int64 `json:"total"`
+	HasMore    bool  `json:"has_more"`
+	NextCursor uint  `json:"next_cursor,omitempty"`
 }
 
 // swagger:model UserAd
 type UserAd struct {
-	ID          uint       `json:"id"`
-	Name        string     `json:"name"`
-	Email       string     `json:"email"`
-	PhoneNumber string     `json:"phone_number"`
-	Location    LocationAd `json:"location"`
+	ID       uint       `json:"id"`
+	Name     string     `json:"name"`
+	Location LocationAd `json:"location"`
 }
 
 // swagger:model SubcategoryAd
 type SubcategoryAd struct {
+	ID       uint   `json:"id"`
 	Name     string `json:"name"`
 	Category string `json:"category"`
 }
@@ -57,3 +95,15 @@ type LocationAd struct {
 	// Example: [123.45, 67.89]
 	Coordinates [2]float64 `json:"coordinates"`
 }
+
+// swagger:model QueuedAd
+type QueuedAd struct {
+	AdResponse
+	Reports []Report `json:"reports"`
+}
+
+// swagger:model ModerationQueue
+type ModerationQueue struct {
+	Items      []QueuedAd `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
@@ -0,0 +1,167 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeInteger = "integer"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+var attributeName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// IsValidAttributeName tells whether name may name an attribute: lower
+// case letters, digits and underscores, starting with a letter.
+func IsValidAttributeName(name string) bool {
+	return attributeName.MatchString(name)
+}
+
+// AttributeDefinition describes an attribute of the ads in a subcategory.
+// Values lists the allowed values of an enum attribute.
+type AttributeDefinition struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type" enums:"string,number,integer,boolean,enum"`
+	Values   []string `json:"values,omitempty"`
+	Required bool     `json:"required"`
+}
+
+// AttributeSchema lists the attributes of the ads in a subcategory. It is
+// stored as JSONB.
+type AttributeSchema []AttributeDefinition
+
+// Attributes holds the attribute values of an ad by name, as decoded from
+// JSON. It is stored as JSONB.
+type Attributes map[string]interface{}
+
+// Check reports the first problem with the schema itself.
+func (s AttributeSchema) Check() error {
+	seen := make(map[string]bool)
+	for _, def := range s {
+		if !IsValidAttributeName(def.Name) {
+			return fmt.Errorf("invalid attribute name %q", def.Name)
+		}
+		if seen[def.Name] {
+			return fmt.Errorf("attribute %s is defined twice", def.Name)
+		}
+		seen[def.Name] = true
+
+		switch def.Type {
+		case AttributeString, AttributeNumber, AttributeInteger, AttributeBoolean:
+			if len(def.Values) > 0 {
+				return fmt.Errorf("attribute %s: only enum attributes have values", def.Name)
+			}
+		case AttributeEnum:
+			if len(def.Values) == 0 {
+				return fmt.Errorf("attribute %s: an enum needs values", def.Name)
+			}
+		default:
+			return fmt.Errorf("attribute %s: unknown type %q", def.Name, def.Type)
+		}
+	}
+	return nil
+}
+
+// Validate reports the first value that does not fit the schema: an
+// unknown attribute, a missing required one or a value of the wrong type.
+func (s AttributeSchema) Validate(values Attributes) error {
+	defs := make(map[string]AttributeDefinition, len(s))
+	for _, def := range s {
+		defs[def.Name] = def
+		if _, ok := values[def.Name]; def.Required && !ok {
+			return fmt.Errorf("attribute %s is required", def.Name)
+		}
+	}
+
+	for name, value := range values {
+		def, ok := defs[name]
+		if !ok {
+			return fmt.Errorf("unknown attribute %s", name)
+		}
+		if !def.accepts(value) {
+			if def.Type == AttributeEnum {
+				return fmt.Errorf("attribute %s must be one of %v", name, def.Values)
+			}
+			return fmt.Errorf("attribute %s must be of type %s", name, def.Type)
+		}
+	}
+	return nil
+}
+
+func (def AttributeDefinition) accepts(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return def.Type == AttributeString || def.Type == AttributeEnum && slices.Contains(def.Values, v)
+	case float64:
+		return def.Type == AttributeNumber || def.Type == AttributeInteger && v == math.Trunc(v)
+	case bool:
+		return def.Type == AttributeBoolean
+	}
+	return false
+}
+
+// AttributeText renders an attribute value the way PostgreSQL's ->>
+// operator does, which attribute filters compare against.
+func AttributeText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return "[]", nil
+	}
+	return jsonValue(s)
+}
+
+func (s *AttributeSchema) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	return jsonValue(a)
+}
+
+func (a *Attributes) Scan(value interface{}) error {
+	return scanJSON(value, a)
+}
+
+func jsonValue(v interface{}) (driver.Value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	case nil:
+		return nil
+	}
+	return errors.New("unsupported JSON column value")
+}
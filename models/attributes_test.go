@@ -0,0 +1,186 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributeSchemaCheck(t *testing.T) {
+	tests := []struct {
+		schema  AttributeSchema
+		wantErr string
+	}{
+		{nil, ""},
+		{AttributeSchema{
+			{Name: "brand", Type: AttributeString},
+			{Name: "power_w", Type: AttributeNumber, Required: true},
+			{Name: "year", Type: AttributeInteger},
+			{Name: "cordless", Type: AttributeBoolean},
+			{Name: "state", Type: AttributeEnum, Values: []string{"new", "used"}},
+		}, ""},
+		{AttributeSchema{{Name: "Brand", Type: AttributeString}}, `invalid attribute name "Brand"`},
+		{AttributeSchema{{Name: "2nd", Type: AttributeString}}, `invalid attribute name "2nd"`},
+		{AttributeSchema{{Name: "", Type: AttributeString}}, `invalid attribute name ""`},
+		{AttributeSchema{
+			{Name: "brand", Type: AttributeString},
+			{Name: "brand", Type: AttributeEnum, Values: []string{"bosch"}},
+		}, "attribute brand is defined twice"},
+		{AttributeSchema{{Name: "brand", Type: AttributeString, Values: []string{"bosch"}}}, "attribute brand: only enum attributes have values"},
+		{AttributeSchema{{Name: "state", Type: AttributeEnum}}, "attribute state: an enum needs values"},
+		{AttributeSchema{{Name: "state", Type: "date"}}, `attribute state: unknown type "date"`},
+	}
+	for _, test := range tests {
+		err := test.schema.Check()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.wantErr {
+			t.Errorf("%v.Check() = %q, want %q", test.schema, got, test.wantErr)
+		}
+	}
+}
+
+func TestAttributeSchemaValidate(t *testing.T) {
+	schema := AttributeSchema{
+		{Name: "brand", Type: AttributeString},
+		{Name: "power_w", Type: AttributeNumber, Required: true},
+		{Name: "year", Type: AttributeInteger},
+		{Name: "cordless", Type: AttributeBoolean},
+		{Name: "state", Type: AttributeEnum, Values: []string{"new", "used"}},
+	}
+
+	tests := []struct {
+		values  Attributes
+		wantErr string
+	}{
+		{Attributes{"power_w": 750.0}, ""},
+		{Attributes{"brand": "Bosch", "power_w": 0.5, "year": 2020.0, "cordless": true, "state": "used"}, ""},
+		{nil, "attribute power_w is required"},
+		{Attributes{"brand": "Bosch"}, "attribute power_w is required"},
+		{Attributes{"power_w": 750.0, "colour": "green"}, "unknown attribute colour"},
+		{Attributes{"power_w": "750"}, "attribute power_w must be of type number"},
+		{Attributes{"power_w": 750.0, "year": 2020.5}, "attribute year must be of type integer"},
+		{Attributes{"power_w": 750.0, "cordless": "yes"}, "attribute cordless must be of type boolean"},
+		{Attributes{"power_w": 750.0, "brand": 1.0}, "attribute brand must be of type string"},
+		{Attributes{"power_w": 750.0, "state": "broken"}, "attribute state must be one of [new used]"},
+	}
+	for _, test := range tests {
+		err := schema.Validate(test.values)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.wantErr {
+			t.Errorf("Validate(%v) = %q, want %q", test.values, got, test.wantErr)
+		}
+	}
+}
+
+func TestAttributeDefinitionAccepts(t *testing.T) {
+	enum := []string{"new", "used"}
+
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  bool
+	}{
+		{AttributeString, "Bosch", true},
+		{AttributeString, "", true},
+		{AttributeString, 1.0, false},
+		{AttributeNumber, 0.5, true},
+		{AttributeNumber, -3.0, true},
+		{AttributeNumber, "0.5", false},
+		{AttributeInteger, 2020.0, true},
+		{AttributeInteger, -1.0, true},
+		{AttributeInteger, 2020.5, false},
+		{AttributeBoolean, false, true},
+		{AttributeBoolean, 0.0, false},
+		{AttributeEnum, "used", true},
+		{AttributeEnum, "Used", false},
+		{AttributeEnum, true, false},
+		// JSON decoding never produces these.
+		{AttributeInteger, 2020, false},
+		{AttributeString, nil, false},
+		{AttributeString, []interface{}{"a"}, false},
+	}
+	for _, test := range tests {
+		def := AttributeDefinition{Name: "attr", Type: test.typ}
+		if test.typ == AttributeEnum {
+			def.Values = enum
+		}
+		if got := def.accepts(test.value); got != test.want {
+			t.Errorf("%s accepts %#v = %v, want %v", test.typ, test.value, got, test.want)
+		}
+	}
+}
+
+func TestAttributesValueScan(t *testing.T) {
+	schemas := []struct {
+		schema AttributeSchema
+		stored string
+	}{
+		{nil, "[]"},
+		{AttributeSchema{}, "[]"},
+		{AttributeSchema{{Name: "state", Type: AttributeEnum, Values: []string{"new"}, Required: true}},
+			`[{"name":"state","type":"enum","values":["new"],"required":true}]`},
+	}
+	for _, test := range schemas {
+		value, err := test.schema.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != test.stored {
+			t.Errorf("%v.Value() = %v, want %s", test.schema, value, test.stored)
+		}
+
+		for _, column := range []interface{}{test.stored, []byte(test.stored)} {
+			var scanned AttributeSchema
+			if err := scanned.Scan(column); err != nil {
+				t.Fatal(err)
+			}
+			if len(scanned) != len(test.schema) || len(test.schema) > 0 && !reflect.DeepEqual(scanned, test.schema) {
+				t.Errorf("Scan(%q) = %v, want %v", test.stored, scanned, test.schema)
+			}
+		}
+	}
+
+	attributes := []struct {
+		attributes Attributes
+		stored     string
+	}{
+		{nil, "{}"},
+		{Attributes{}, "{}"},
+		{Attributes{"brand": "Bosch", "cordless": true, "power_w": 750.0}, `{"brand":"Bosch","cordless":true,"power_w":750}`},
+	}
+	for _, test := range attributes {
+		value, err := test.attributes.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != test.stored {
+			t.Errorf("%v.Value() = %v, want %s", test.attributes, value, test.stored)
+		}
+
+		for _, column := range []interface{}{test.stored, []byte(test.stored)} {
+			var scanned Attributes
+			if err := scanned.Scan(column); err != nil {
+				t.Fatal(err)
+			}
+			if len(scanned) != len(test.attributes) || len(test.attributes) > 0 && !reflect.DeepEqual(scanned, test.attributes) {
+				t.Errorf("Scan(%q) = %v, want %v", test.stored, scanned, test.attributes)
+			}
+		}
+	}
+
+	var scanned Attributes
+	if err := scanned.Scan(nil); err != nil || scanned != nil {
+		t.Errorf("Scan(nil) = %v, %v; want nil, nil", scanned, err)
+	}
+	if err := scanned.Scan(42); err == nil {
+		t.Error("Scan(42) succeeded")
+	}
+	if err := scanned.Scan("{"); err == nil {
+		t.Error("Scan of malformed JSON succeeded")
+	}
+}
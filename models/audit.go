@@ -0,0 +1,105 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// Entities recorded in the audit log besides EntityCategory and
+// EntitySubcategory. A favorite has no ID of its own and is recorded
+// under the ID of the ad.
+const (
+	EntityAd           = "ad"
+	EntityUser         = "user"
+	EntitySavedSearch  = "saved_search"
+	EntityReport       = "report"
+	EntityFavorite     = "favorite"
+	EntityConversation = "conversation"
+	EntityMessage      = "message"
+	EntityNotification = "notification"
+)
+
+// Audited operations. A restore brings back a deleted record.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditEntry records a write to one record: who made it, in which request
+// and what it changed. ActorID is nil for writes nobody was signed in for.
+type AuditEntry struct {
+	ID        uint         `gorm:"primaryKey;autoIncrement" json:"id"`
+	ActorID   *uint        `json:"actor_id"`
+	Entity    string       `json:"entity"`
+	EntityID  uint         `json:"entity_id"`
+	Action    string       `json:"action"`
+	Changes   AuditChanges `json:"changes" gorm:"type:jsonb"`
+	RequestID string       `json:"request_id"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// Change holds the JSON values of a field before and after a write.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges holds the changed fields of a record by JSON name. It is
+// stored as JSONB.
+type AuditChanges map[string]Change
+
+// Diff compares the JSON forms of before and after field by field and
+// returns the fields that differ. Either may be nil, for a created or a
+// deleted record, in which case every field of the other one is returned.
+// Fields hidden from JSON, such as password hashes, never show up.
+func Diff(before, after interface{}) (AuditChanges, error) {
+	old, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(AuditChanges)
+	for name, value := range old {
+		if !reflect.DeepEqual(value, updated[name]) {
+			changes[name] = Change{Before: value, After: updated[name]}
+		}
+	}
+	for name, value := range updated {
+		if _, ok := old[name]; !ok && value != nil {
+			changes[name] = Change{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	return jsonValue(c)
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
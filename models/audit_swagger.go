@@ -0,0 +1,7 @@
+package models
+
+// swagger:model AuditLog
+type AuditLog struct {
+	Items      []AuditEntry `json:"items"`
+	Pagination Pagination   `json:"pagination"`
+}
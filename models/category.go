@@ -1,6 +1,48 @@
 package models
 
+import (
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Category is a node of the category tree. Path lists the IDs from the
+// root down to the category itself, each followed by a slash, so the
+// descendants of a category are the categories whose path starts with its
+// own.
 type Category struct {
-	ID   uint   `gorm:"primaryKey;autoIncrement" json:"id"`
-	Name string `json:"name"`
+	ID       uint   `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name     string `json:"name"`
+	ParentID *uint  `json:"parent_id"`
+	Path     string `json:"path"`
+	// Translations holds the name in other locales than DefaultLocale.
+	Translations Translations   `json:"translations" gorm:"-"`
+	DeletedAt    gorm.DeletedAt `json:"-"`
+}
+
+// PlaceUnder sets the parent and path of the category for it to sit under
+// parent, or at the root when parent is nil. The category must have an ID.
+func (c *Category) PlaceUnder(parent *Category) {
+	c.ParentID = nil
+	c.Path = ""
+	if parent != nil {
+		c.ParentID = &parent.ID
+		c.Path = parent.Path
+	}
+	c.Path += strconv.FormatUint(uint64(c.ID), 10) + "/"
+}
+
+// SameParent tells whether both categories sit under the same parent.
+func (c Category) SameParent(other Category) bool {
+	if c.ParentID == nil || other.ParentID == nil {
+		return c.ParentID == other.ParentID
+	}
+	return *c.ParentID == *other.ParentID
+}
+
+// Contains tells whether other is the category itself or one of its
+// descendants.
+func (c Category) Contains(other Category) bool {
+	return strings.HasPrefix(other.Path, c.Path)
 }
@@ -0,0 +1,25 @@
+package models
+
+// CategoryNode is a category in the tree with its subcategories and the
+// categories below it.
+type CategoryNode struct {
+	ID            uint              `json:"id"`
+	Name          string            `json:"name"`
+	Subcategories []SubcategoryNode `json:"subcategories"`
+	Children      []CategoryNode    `json:"children"`
+}
+
+type SubcategoryNode struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// DeleteConflict is returned when a category or subcategory still has
+// records that belong to it. Categories and Subcategories are set only
+// for categories; Ads counts the ads anywhere below the node.
+type DeleteConflict struct {
+	Error         string `json:"error"`
+	Categories    int64  `json:"categories,omitempty"`
+	Subcategories int64  `json:"subcategories,omitempty"`
+	Ads           int64  `json:"ads"`
+}
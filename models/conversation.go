@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Conversation is a message thread between a prospective buyer and the
+// owner of an ad. There is at most one per ad and buyer.
+type Conversation struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID          uint       `json:"ad_id"`
+	BuyerID       uint       `json:"buyer_id"`
+	SellerID      uint       `json:"seller_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastMessageAt *time.Time `json:"last_message_at"`
+}
+
+// HasParticipant reports whether the user is the buyer or the seller.
+func (c Conversation) HasParticipant(userID uint) bool {
+	return c.BuyerID == userID || c.SellerID == userID
+}
+
+type Message struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	ConversationID uint       `json:"conversation_id"`
+	SenderID       uint       `json:"sender_id"`
+	Body           string     `json:"body"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at"`
+}
+
+// ConversationSummary is a conversation as listed for one of its
+// participants: Unread counts the messages the other side sent that the
+// participant has not read yet.
+type ConversationSummary struct {
+	Conversation
+	AdTitle string `json:"ad_title"`
+	Unread  int64  `json:"unread"`
+}
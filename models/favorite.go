@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+// Favorite is an ad bookmarked by a user.
+type Favorite struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	AdID      uint      `gorm:"primaryKey" json:"ad_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// Reasons a user can report an ad for.
+const (
+	ReportScam          = "scam"
+	ReportProhibited    = "prohibited"
+	ReportSpam          = "spam"
+	ReportDuplicate     = "duplicate"
+	ReportWrongCategory = "wrong_category"
+	ReportOffensive     = "offensive"
+	ReportOther         = "other"
+)
+
+// Report flags an ad for moderators. It is open until a moderator acts
+// on the ad, which resolves every open report of it.
+type Report struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID       uint       `json:"ad_id"`
+	UserID     uint       `json:"user_id"`
+	Reason     string     `json:"reason"`
+	Comment    string     `json:"comment"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+	ActionID   *uint      `json:"action_id"`
+}
+
+// Moderation actions.
+const (
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionHide    = "hide"
+)
+
+// ModerationAction records a moderator's decision on an ad and the status
+// change it made.
+type ModerationAction struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	AdID        uint      `json:"ad_id"`
+	ModeratorID uint      `json:"moderator_id"`
+	Action      string    `json:"action"`
+	Reason      string    `json:"reason"`
+	FromStatus  string    `json:"from_status"`
+	ToStatus    string    `json:"to_status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ModeratedStatus returns the status an action moves the ad to at the
+// given time. Approving brings back a rejected, hidden or pending ad, as
+// expired once its expiry has passed so that the owner has to renew it,
+// and leaves any other status alone.
+func ModeratedStatus(action string, ad Advertisement, now time.Time) string {
+	switch action {
+	case ActionReject:
+		return StatusRejected
+	case ActionHide:
+		return StatusHidden
+	}
+	if ad.Status != StatusRejected && ad.Status != StatusHidden && ad.Status != StatusPending {
+		return ad.Status
+	}
+	if !ad.ExpiresAt.After(now) {
+		return StatusExpired
+	}
+	return StatusActive
+}
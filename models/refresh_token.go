@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is a long-lived session credential. Only the SHA-256 hash
+// of the token is stored; a token is usable until it expires or is revoked.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID    uint       `json:"user_id"`
+	TokenHash string     `gorm:"unique" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
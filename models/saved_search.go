@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// SavedSearch is a search a user wants to be alerted about. Unset
+// criteria match every ad; Lat, Lon and Radius are set together.
+type SavedSearch struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        uint      `json:"user_id"`
+	Name          string    `json:"name"`
+	CategoryID    *uint     `json:"category_id"`
+	SubcategoryID *uint     `json:"subcategory_id"`
+	PriceMin      *int64    `json:"price_min"`
+	PriceMax      *int64    `json:"price_max"`
+	Currency      string    `json:"currency"`
+	Query         string    `json:"query"`
+	Lat           *float64  `json:"lat"`
+	Lon           *float64  `json:"lon"`
+	Radius        *float64  `json:"radius"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Notification tells a user that a new ad matches one of their saved
+// searches.
+type Notification struct {
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	UserID        uint       `json:"user_id"`
+	AdID          uint       `json:"ad_id"`
+	SavedSearchID uint       `json:"saved_search_id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ReadAt        *time.Time `json:"read_at"`
+}
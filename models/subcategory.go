@@ -1,8 +1,18 @@
 package models
 
+import (
+	"gorm.io/gorm"
+)
+
 type Subcategory struct {
 	ID         uint     `gorm:"primaryKey;autoIncrement" json:"id"`
 	Name       string   `json:"name"`
 	CategoryID uint     `json:"category_id"`
 	Category   Category `gorm:"foreignKey:CategoryID" json:"-"`
+	// AttributeSchema defines the attributes of the ads in the
+	// subcategory.
+	AttributeSchema AttributeSchema `json:"attribute_schema" gorm:"type:jsonb"`
+	// Translations holds the name in other locales than DefaultLocale.
+	Translations Translations   `json:"translations" gorm:"-"`
+	DeletedAt    gorm.DeletedAt `json:"-"`
 }
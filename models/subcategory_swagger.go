@@ -2,7 +2,9 @@ package models
 
 // swagger: model SubcategoryResponse
 type SubcategoryResponse struct {
-	ID       uint   `json:"id"`
-	Name     string `json:"name"`
-	Category string `json:"category"`
+	ID              uint            `json:"id"`
+	Name            string          `json:"name"`
+	Category        string          `json:"category"`
+	AttributeSchema AttributeSchema `json:"attribute_schema"`
+	Translations    Translations    `json:"translations"`
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the language of the Name of categories and
+// subcategories. Names in other languages are kept as translations.
+const DefaultLocale = "ru"
+
+// Entities whose names are translated.
+const (
+	EntityCategory    = "category"
+	EntitySubcategory = "subcategory"
+)
+
+var locale = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// IsValidLocale tells whether s is a lower case BCP 47 language tag such
+// as "en" or "en-us".
+func IsValidLocale(s string) bool {
+	return locale.MatchString(s)
+}
+
+// Translation is the name of a category or subcategory in one locale.
+type Translation struct {
+	Entity   string `gorm:"primaryKey"`
+	EntityID uint   `gorm:"primaryKey"`
+	Locale   string `gorm:"primaryKey"`
+	Name     string
+}
+
+// Translations holds a name by locale.
+type Translations map[string]string
+
+// Check reports the first locale that is invalid, is a variant of
+// DefaultLocale or has an empty name.
+func (t Translations) Check() error {
+	locales := make([]string, 0, len(t))
+	for locale := range t {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	for _, locale := range locales {
+		switch {
+		case !IsValidLocale(locale):
+			return fmt.Errorf("invalid locale %q", locale)
+		case locale == DefaultLocale || strings.HasPrefix(locale, DefaultLocale+"-"):
+			return fmt.Errorf("the name itself is in %s", DefaultLocale)
+		case t[locale] == "":
+			return fmt.Errorf("empty name in %s", locale)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the translation into the first of the locales that has
+// one, or name when none has.
+func (t Translations) Resolve(name string, locales []string) string {
+	for _, locale := range locales {
+		if translated, ok := t[locale]; ok {
+			return translated
+		}
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestTranslationsCheck(t *testing.T) {
+	tests := []struct {
+		translations Translations
+		wantErr      string
+	}{
+		{nil, ""},
+		{Translations{"en": "Garden", "en-gb": "Garden", "uk": "Сад"}, ""},
+		{Translations{"rue": "Загорода"}, ""},
+		{Translations{"EN": "Garden"}, `invalid locale "EN"`},
+		{Translations{"en_us": "Garden"}, `invalid locale "en_us"`},
+		{Translations{"e": "Garden"}, `invalid locale "e"`},
+		{Translations{"en-": "Garden"}, `invalid locale "en-"`},
+		{Translations{"ru": "Сад"}, "the name itself is in ru"},
+		{Translations{"ru-ua": "Сад"}, "the name itself is in ru"},
+		{Translations{"en": ""}, "empty name in en"},
+		// The first locale in sorted order is reported.
+		{Translations{"fr": "", "de": ""}, "empty name in de"},
+	}
+	for _, test := range tests {
+		err := test.translations.Check()
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.wantErr {
+			t.Errorf("%v.Check() = %q, want %q", test.translations, got, test.wantErr)
+		}
+	}
+}
+
+func TestTranslationsResolve(t *testing.T) {
+	translations := Translations{"en": "Garden", "de": "Garten"}
+
+	tests := []struct {
+		locales []string
+		want    string
+	}{
+		{nil, "Сад"},
+		{[]string{"en-us", "en"}, "Garden"},
+		{[]string{"fr", "de", "en"}, "Garten"},
+		{[]string{"fr"}, "Сад"},
+	}
+	for _, test := range tests {
+		if got := translations.Resolve("Сад", test.locales); got != test.want {
+			t.Errorf("Resolve(%q) = %q, want %q", test.locales, got, test.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/sciphilib/go-dacha/common"
+	"gorm.io/gorm"
 )
 
 type User struct {
@@ -12,4 +13,20 @@ type User struct {
 	LocationText common.GeoJSONText `json:"location" gorm:"-"`
 	LocationEWKB []byte             `gorm:"column:location" json:"-"`
 	PhoneNumber  string             `json:"phone_number" gorm:"unique"`
+	Role         string             `json:"role" gorm:"default:user"`
+	DeletedAt    gorm.DeletedAt     `json:"-"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
 }
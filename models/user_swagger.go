@@ -11,8 +11,8 @@ type UserInputS struct {
 
 // swagger: model AuthInput
 type AuthInputS struct {
-	Email    string `json:email`
-	Password string `json:password`
+	Email    string `json:"email"`
+	Password string `json:"password"`
 }
 
 // swagger:model UserResponse
@@ -22,6 +22,7 @@ type UserResponse struct {
 	Email       string       `json:"email"`
 	Location    UserLocation `json:"location"`
 	PhoneNumber string       `json:"phone_number"`
+	Role        string       `json:"role"`
 }
 
 // swagger:model UserUpdate
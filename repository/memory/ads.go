@@ -0,0 +1,508 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type adRepository struct {
+	*store
+}
+
+// details joins an ad with its subcategory, category and owner. The caller
+// must hold the lock.
+func (s *store) details(ad models.Advertisement) models.AdDetails {
+	subcategory := s.subcategories[ad.Subcategory_id]
+	ad.Subcategory = models.SubcategoryWithCategory{
+		ID:         ad.Subcategory_id,
+		Name:       subcategory.Name,
+		CategoryID: subcategory.CategoryID,
+		Category:   s.categories[subcategory.CategoryID].Name,
+	}
+	ad.LocationText = locationJSON(ad.LocationEWKB)
+	ad.Pictures = append(ad.Pictures[:0:0], ad.Pictures...)
+	ad.PicturesText = append([]string{}, ad.Pictures...)
+
+	return models.AdDetails{
+		Advertisement: ad,
+		User:          s.user(s.users[ad.User_id]),
+	}
+}
+
+// searchTerms splits a web search query into the words an ad must contain
+// and the words it must not. Quotes and OR are ignored.
+func searchTerms(query string) (include, exclude []string) {
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		word = strings.Trim(word, `"`)
+		switch {
+		case word == "" || word == "or":
+		case strings.HasPrefix(word, "-"):
+			if word = strings.TrimPrefix(word, "-"); word != "" {
+				exclude = append(exclude, word)
+			}
+		default:
+			include = append(include, word)
+		}
+	}
+	return include, exclude
+}
+
+// rank counts term occurrences, weighting the title above the description
+// like the search_vector column does. Zero means the ad does not match.
+func rank(ad models.Advertisement, query string) int {
+	include, exclude := searchTerms(query)
+	title, description := strings.ToLower(ad.Title), strings.ToLower(ad.Description)
+
+	for _, word := range exclude {
+		if strings.Contains(title, word) || strings.Contains(description, word) {
+			return 0
+		}
+	}
+
+	score := 0
+	for _, word := range include {
+		n := 4*strings.Count(title, word) + strings.Count(description, word)
+		if n == 0 {
+			return 0
+		}
+		score += n
+	}
+	return max(score, 1)
+}
+
+func (s *store) matches(ad models.Advertisement, f repository.AdFilter) bool {
+	if f.CategoryID != 0 && !s.inCategory(ad, f.CategoryID) {
+		return false
+	}
+	if f.SubcategoryID != 0 && ad.Subcategory_id != f.SubcategoryID {
+		return false
+	}
+	if f.PriceMin != nil && (ad.Price.Free || ad.Price.Amount < *f.PriceMin) {
+		return false
+	}
+	if f.PriceMax != nil && !ad.Price.Free && ad.Price.Amount > *f.PriceMax {
+		return false
+	}
+	if f.Currency != "" && !ad.Price.Free && ad.Price.Currency != f.Currency {
+		return false
+	}
+	if f.From != nil && ad.Datetime.Before(*f.From) {
+		return false
+	}
+	if f.To != nil && ad.Datetime.After(*f.To) {
+		return false
+	}
+	if f.UserID != 0 && ad.User_id != f.UserID {
+		return false
+	}
+	if f.Query != "" && rank(ad, f.Query) == 0 {
+		return false
+	}
+	if f.Status != "" && ad.Status != f.Status {
+		return false
+	}
+	for _, attr := range f.Attributes {
+		if !matchesAttribute(ad.Attributes, attr) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAttribute(attributes models.Attributes, f repository.AttributeFilter) bool {
+	value, ok := attributes[f.Name]
+	if !ok {
+		return false
+	}
+	if f.Value != "" && models.AttributeText(value) != f.Value {
+		return false
+	}
+	if f.Min != nil || f.Max != nil {
+		number, ok := value.(float64)
+		if !ok || f.Min != nil && number < *f.Min || f.Max != nil && number > *f.Max {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesGeo(location orb.Point, f repository.GeoFilter) bool {
+	if f.Radius != 0 && distance(location, orb.Point{f.Lon, f.Lat}) > f.Radius {
+		return false
+	}
+	if f.BBox != nil {
+		bound := orb.Bound{Min: orb.Point{f.BBox[0], f.BBox[1]}, Max: orb.Point{f.BBox[2], f.BBox[3]}}
+		if !bound.Contains(location) {
+			return false
+		}
+	}
+	return true
+}
+
+// less orders ads the way the SQL implementation does: by price when
+// asked to, then by relevance to the search text, and by ID otherwise.
+func less(a, b models.Advertisement, f repository.AdFilter) bool {
+	switch {
+	case f.Sort == repository.SortPriceAsc:
+		if a.Price.Free != b.Price.Free {
+			return a.Price.Free
+		}
+		if a.Price.Amount != b.Price.Amount {
+			return a.Price.Amount < b.Price.Amount
+		}
+	case f.Sort == repository.SortPriceDesc:
+		if a.Price.Free != b.Price.Free {
+			return b.Price.Free
+		}
+		if a.Price.Amount != b.Price.Amount {
+			return a.Price.Amount > b.Price.Amount
+		}
+	case f.Query != "":
+		if ra, rb := rank(a, f.Query), rank(b, f.Query); ra != rb {
+			return ra > rb
+		}
+	}
+	return a.ID < b.ID
+}
+
+// page returns one page of ads, which must already be in order.
+func page(ads []models.AdDetails, total int, limit, offset int) repository.AdPage {
+	result := repository.AdPage{Total: int64(total)}
+
+	ads = ads[min(offset, len(ads)):]
+	if len(ads) > limit {
+		ads = ads[:limit]
+		result.HasMore = true
+	}
+	result.Ads = ads
+
+	return result
+}
+
+func (r *adRepository) List(ctx context.Context, filter repository.AdFilter) (repository.AdPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []models.Advertisement
+	for _, ad := range r.ads {
+		if r.matches(ad, filter) {
+			matched = append(matched, ad)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return less(matched[i], matched[j], filter) })
+
+	var ads []models.AdDetails
+	for _, ad := range matched {
+		if ad.ID > filter.Cursor {
+			ads = append(ads, r.details(ad))
+		}
+	}
+
+	return page(ads, len(matched), filter.Limit, filter.Offset), nil
+}
+
+func (r *adRepository) Search(ctx context.Context, filter repository.AdFilter, geo repository.GeoFilter) (repository.AdPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	origin := orb.Point{geo.Lon, geo.Lat}
+
+	var ads []models.AdDetails
+	for _, ad := range r.ads {
+		location, ok := point(ad.LocationEWKB)
+		if !ok || !r.matches(ad, filter) || !matchesGeo(location, geo) {
+			continue
+		}
+		details := r.details(ad)
+		d := distance(location, origin)
+		details.Distance = &d
+		ads = append(ads, details)
+	}
+	sort.Slice(ads, func(i, j int) bool {
+		if *ads[i].Distance != *ads[j].Distance {
+			return *ads[i].Distance < *ads[j].Distance
+		}
+		return ads[i].ID < ads[j].ID
+	})
+
+	return page(ads, len(ads), filter.Limit, filter.Offset), nil
+}
+
+func (r *adRepository) Newest(ctx context.Context) ([]models.AdDetails, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ads := make([]models.AdDetails, 0, len(r.ads))
+	for _, ad := range r.ads {
+		if ad.Status == models.StatusActive {
+			ads = append(ads, r.details(ad))
+		}
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].Datetime.After(ads[j].Datetime) })
+
+	return ads, nil
+}
+
+func (r *adRepository) NearestTo(ctx context.Context, userID uint) ([]models.AdDetails, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	origin, hasOrigin := point(r.users[userID].LocationEWKB)
+
+	ads := make([]models.AdDetails, 0, len(r.ads))
+	for _, ad := range r.ads {
+		if ad.Status != models.StatusActive {
+			continue
+		}
+		details := r.details(ad)
+		if location, ok := point(ad.LocationEWKB); ok && hasOrigin {
+			d := distance(location, origin)
+			details.Distance = &d
+		}
+		ads = append(ads, details)
+	}
+	sort.Slice(ads, func(i, j int) bool {
+		a, b := ads[i].Distance, ads[j].Distance
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	return ads, nil
+}
+
+func (r *adRepository) GetDetails(ctx context.Context, id uint) (models.AdDetails, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return models.AdDetails{}, repository.ErrNotFound
+	}
+	return r.details(ad), nil
+}
+
+func (r *adRepository) Get(ctx context.Context, id uint) (models.Advertisement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return models.Advertisement{}, repository.ErrNotFound
+	}
+	return ad, nil
+}
+
+// checkAd enforces the subcategory and owner foreign keys.
+func (s *store) checkAd(ad *models.Advertisement) error {
+	if _, ok := s.subcategories[ad.Subcategory_id]; !ok {
+		return repository.ErrForeignKey
+	}
+	if _, ok := s.users[ad.User_id]; !ok {
+		return repository.ErrForeignKey
+	}
+	return nil
+}
+
+func (r *adRepository) Create(ctx context.Context, ad *models.Advertisement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkAd(ad); err != nil {
+		return err
+	}
+
+	if ad.Status == "" {
+		ad.Status = models.StatusActive
+	}
+	ad.ID = r.nextID("advertisements")
+	r.ads[ad.ID] = *ad
+	return nil
+}
+
+func (r *adRepository) Update(ctx context.Context, ad *models.Advertisement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.ads[ad.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkAd(ad); err != nil {
+		return err
+	}
+
+	ad.Pictures = stored.Pictures
+	ad.Status = stored.Status
+	ad.ExpiresAt = stored.ExpiresAt
+	ad.ReviewedAt = stored.ReviewedAt
+	ad.MatchedAt = stored.MatchedAt
+	r.ads[ad.ID] = *ad
+	return nil
+}
+
+func (r *adRepository) SetStatus(ctx context.Context, id uint, from, to string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok || ad.Status != from {
+		return repository.ErrNotFound
+	}
+	ad.Status = to
+	if from == models.StatusRejected {
+		ad.ReviewedAt = nil
+	}
+	if to == models.StatusActive {
+		ad.MatchedAt = nil
+	}
+	r.ads[id] = ad
+	return nil
+}
+
+func (r *adRepository) Renew(ctx context.Context, id uint, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok || (ad.Status != models.StatusDraft && ad.Status != models.StatusActive && ad.Status != models.StatusExpired) {
+		return repository.ErrNotFound
+	}
+	ad.ExpiresAt = until
+	if ad.Status == models.StatusExpired {
+		ad.Status = models.StatusActive
+	}
+	r.ads[id] = ad
+	return nil
+}
+
+func (r *adRepository) Expire(ctx context.Context, now time.Time) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uint
+	for id, ad := range r.ads {
+		if ad.Status == models.StatusActive && !ad.ExpiresAt.After(now) {
+			ad.Status = models.StatusExpired
+			r.ads[id] = ad
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func (r *adRepository) Unmatched(ctx context.Context, limit int) ([]uint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uint
+	for id, ad := range r.ads {
+		if ad.MatchedAt == nil && ad.Status == models.StatusActive {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (r *adRepository) MarkMatched(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if ad, ok := r.ads[id]; ok {
+		now := time.Now()
+		ad.MatchedAt = &now
+		r.ads[id] = ad
+	}
+	return nil
+}
+
+func (r *adRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	ad.DeletedAt = deletedAt(time.Now())
+	delete(r.ads, id)
+	r.deletedAds[id] = ad
+	return nil
+}
+
+func (r *adRepository) Restore(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.deletedAds[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := r.subcategories[ad.Subcategory_id]; !ok {
+		return repository.ErrForeignKey
+	}
+	if _, ok := r.users[ad.User_id]; !ok {
+		return repository.ErrForeignKey
+	}
+
+	ad.DeletedAt = gorm.DeletedAt{}
+	delete(r.deletedAds, id)
+	r.ads[id] = ad
+	return nil
+}
+
+// updatePictures replaces the pictures of an ad with the result of
+// change, unless change fails.
+func (r *adRepository) updatePictures(id uint, change func([]string) ([]string, error)) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	pictures, err := change(append([]string{}, ad.Pictures...))
+	if err != nil {
+		return nil, err
+	}
+	ad.Pictures = pictures
+	r.ads[id] = ad
+	return append([]string{}, ad.Pictures...), nil
+}
+
+func (r *adRepository) AppendPictures(ctx context.Context, id uint, pictures []string, limit int) ([]string, error) {
+	return r.updatePictures(id, func(current []string) ([]string, error) {
+		if len(current)+len(pictures) > limit {
+			return nil, repository.ErrLimit
+		}
+		return append(current, pictures...), nil
+	})
+}
+
+func (r *adRepository) RemovePicture(ctx context.Context, id uint, picture string) ([]string, error) {
+	return r.updatePictures(id, func(current []string) ([]string, error) {
+		return slices.DeleteFunc(current, func(p string) bool { return p == picture }), nil
+	})
+}
+
+func (r *adRepository) SetPictures(ctx context.Context, id uint, pictures []string) ([]string, error) {
+	return r.updatePictures(id, func([]string) ([]string, error) {
+		return append([]string{}, pictures...), nil
+	})
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type auditRepository struct {
+	*store
+}
+
+func (r *auditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = r.nextID("audit_entries")
+	entry.CreatedAt = time.Now()
+	r.auditEntries[entry.ID] = *entry
+	return nil
+}
+
+func auditMatches(entry models.AuditEntry, f repository.AuditFilter) bool {
+	switch {
+	case f.ActorID != 0 && (entry.ActorID == nil || *entry.ActorID != f.ActorID):
+		return false
+	case f.Entity != "" && entry.Entity != f.Entity:
+		return false
+	case f.EntityID != 0 && entry.EntityID != f.EntityID:
+		return false
+	case f.Action != "" && entry.Action != f.Action:
+		return false
+	case f.RequestID != "" && entry.RequestID != f.RequestID:
+		return false
+	case f.From != nil && entry.CreatedAt.Before(*f.From):
+		return false
+	case f.To != nil && entry.CreatedAt.After(*f.To):
+		return false
+	}
+	return true
+}
+
+func (r *auditRepository) List(ctx context.Context, filter repository.AuditFilter) (repository.AuditPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []models.AuditEntry
+	for _, entry := range r.auditEntries {
+		if auditMatches(entry, filter) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+
+	result := repository.AuditPage{Total: int64(len(entries))}
+	entries = entries[min(filter.Offset, len(entries)):]
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		result.HasMore = true
+	}
+	result.Entries = entries
+
+	return result, nil
+}
@@ -0,0 +1,548 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type categoryRepository struct {
+	*store
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	categories := make([]models.Category, 0, len(r.categories))
+	for _, category := range r.categories {
+		categories = append(categories, r.category(category))
+	}
+	sort.Slice(categories, func(i, j int) bool { return categories[i].ID < categories[j].ID })
+
+	return categories, nil
+}
+
+func (r *categoryRepository) Get(ctx context.Context, id uint) (models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return models.Category{}, repository.ErrNotFound
+	}
+	return r.category(category), nil
+}
+
+// checkCategoryName enforces the unique (parent_id, name) index, which
+// covers live categories only.
+func (s *store) checkCategoryName(category *models.Category) error {
+	for _, other := range s.categories {
+		if other.ID != category.ID && other.Name == category.Name && other.SameParent(*category) {
+			return repository.ErrDuplicate
+		}
+	}
+	return nil
+}
+
+func (r *categoryRepository) Descendants(ctx context.Context, id uint) ([]models.Category, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	category, ok := r.categories[id]
+	if !ok {
+		return nil, nil
+	}
+
+	var descendants []models.Category
+	for _, other := range r.categories {
+		if other.ID != id && category.Contains(other) {
+			descendants = append(descendants, r.category(other))
+		}
+	}
+	sort.Slice(descendants, func(i, j int) bool { return descendants[i].Path < descendants[j].Path })
+
+	return descendants, nil
+}
+
+// parentOf returns the live category with the given ID, or nil for the
+// root. The caller must hold the lock.
+func (s *store) parentOf(id *uint) (*models.Category, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	parent, ok := s.categories[*id]
+	if !ok {
+		return nil, repository.ErrForeignKey
+	}
+	return &parent, nil
+}
+
+// move puts the category under parent together with everything below it,
+// deleted or not. The caller must hold the write lock.
+func (s *store) move(category models.Category, parent *models.Category) {
+	moved := category
+	moved.PlaceUnder(parent)
+
+	for _, categories := range []map[uint]models.Category{s.categories, s.deletedCategories} {
+		for id, other := range categories {
+			if category.Contains(other) {
+				other.Path = moved.Path + other.Path[len(category.Path):]
+				if id == category.ID {
+					other.ParentID = moved.ParentID
+				}
+				categories[id] = other
+			}
+		}
+	}
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkCategoryName(category); err != nil {
+		return err
+	}
+	parent, err := r.parentOf(category.ParentID)
+	if err != nil {
+		return err
+	}
+
+	category.ID = r.nextID("categories")
+	category.PlaceUnder(parent)
+	r.saveTranslations(models.EntityCategory, category.ID, category.Translations)
+	stored := *category
+	stored.Translations = nil
+	r.categories[category.ID] = stored
+	*category = r.category(stored)
+	return nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.categories[category.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkCategoryName(category); err != nil {
+		return err
+	}
+
+	if !current.SameParent(*category) {
+		parent, err := r.parentOf(category.ParentID)
+		if err != nil {
+			return err
+		}
+		r.move(current, parent)
+	}
+
+	current = r.categories[category.ID]
+	current.Name = category.Name
+	r.categories[category.ID] = current
+	r.saveTranslations(models.EntityCategory, category.ID, category.Translations)
+	*category = r.category(current)
+	return nil
+}
+
+func (r *categoryRepository) Dependents(ctx context.Context, id uint) (repository.Dependents, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var dependents repository.Dependents
+	for _, category := range r.categories {
+		if category.ParentID != nil && *category.ParentID == id {
+			dependents.Categories++
+		}
+	}
+	for _, subcategory := range r.subcategories {
+		if subcategory.CategoryID == id {
+			dependents.Subcategories++
+		}
+	}
+	for _, ad := range r.ads {
+		if r.inCategory(ad, id) {
+			dependents.Ads++
+		}
+	}
+	return dependents, nil
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id uint, opts repository.DeleteOptions) (repository.Deleted, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted repository.Deleted
+	category, ok := r.categories[id]
+	if !ok {
+		return deleted, repository.ErrNotFound
+	}
+
+	var children []models.Category
+	for _, child := range r.categories {
+		if child.ParentID != nil && *child.ParentID == id {
+			children = append(children, child)
+		}
+	}
+	var subcategories []models.Subcategory
+	for _, subcategory := range r.subcategories {
+		if subcategory.CategoryID == id {
+			subcategories = append(subcategories, subcategory)
+		}
+	}
+
+	now := deletedAt(time.Now())
+	switch {
+	case opts.ReassignTo != 0:
+		parent, err := r.parentOf(&opts.ReassignTo)
+		if err != nil {
+			return deleted, err
+		}
+		for _, subcategory := range subcategories {
+			subcategory.CategoryID = opts.ReassignTo
+			if err := r.checkSubcategory(&subcategory); err != nil {
+				return deleted, err
+			}
+		}
+		for _, child := range children {
+			child.ParentID = &opts.ReassignTo
+			if err := r.checkCategoryName(&child); err != nil {
+				return deleted, err
+			}
+		}
+
+		for _, child := range children {
+			r.move(child, parent)
+		}
+		for _, subcategory := range subcategories {
+			subcategory.CategoryID = opts.ReassignTo
+			r.subcategories[subcategory.ID] = subcategory
+		}
+	case opts.Cascade:
+		deleted.Ads = r.deleteAds(func(ad models.Advertisement) bool {
+			return r.inCategory(ad, id)
+		}, now)
+		for subcategoryID, subcategory := range r.subcategories {
+			if category.Contains(r.categories[subcategory.CategoryID]) {
+				deleted.Subcategories = append(deleted.Subcategories, r.subcategory(subcategory))
+				subcategory.DeletedAt = now
+				delete(r.subcategories, subcategoryID)
+				r.deletedSubcategories[subcategoryID] = subcategory
+			}
+		}
+		for descendantID, descendant := range r.categories {
+			if descendantID != id && category.Contains(descendant) {
+				deleted.Categories = append(deleted.Categories, r.category(descendant))
+				descendant.DeletedAt = now
+				delete(r.categories, descendantID)
+				r.deletedCategories[descendantID] = descendant
+			}
+		}
+		sort.Slice(deleted.Subcategories, func(i, j int) bool {
+			return deleted.Subcategories[i].ID < deleted.Subcategories[j].ID
+		})
+		sort.Slice(deleted.Categories, func(i, j int) bool {
+			return deleted.Categories[i].Path < deleted.Categories[j].Path
+		})
+	default:
+		if len(children) > 0 || len(subcategories) > 0 {
+			return deleted, repository.ErrForeignKey
+		}
+	}
+
+	category.DeletedAt = now
+	delete(r.categories, id)
+	r.deletedCategories[id] = category
+	return deleted, nil
+}
+
+func (r *categoryRepository) Restore(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	category, ok := r.deletedCategories[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if _, err := r.parentOf(category.ParentID); err != nil {
+		return err
+	}
+
+	// Everything below that was deleted at the same time went with the
+	// category.
+	deletedAt := category.DeletedAt.Time
+	restored := make(map[uint]models.Category)
+	for descendantID, descendant := range r.deletedCategories {
+		if category.Contains(descendant) && descendant.DeletedAt.Time.Equal(deletedAt) {
+			restored[descendantID] = descendant
+		}
+	}
+	for _, descendant := range restored {
+		if err := r.checkCategoryName(&descendant); err != nil {
+			return err
+		}
+	}
+	for subcategoryID, subcategory := range r.deletedSubcategories {
+		if _, ok := restored[subcategory.CategoryID]; ok && subcategory.DeletedAt.Time.Equal(deletedAt) {
+			r.restoreAds(subcategoryID, deletedAt)
+			subcategory.DeletedAt = gorm.DeletedAt{}
+			delete(r.deletedSubcategories, subcategoryID)
+			r.subcategories[subcategoryID] = subcategory
+		}
+	}
+	for descendantID, descendant := range restored {
+		descendant.DeletedAt = gorm.DeletedAt{}
+		delete(r.deletedCategories, descendantID)
+		r.categories[descendantID] = descendant
+	}
+	return nil
+}
+
+func (r *categoryRepository) Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	live := func(id uint) bool {
+		_, ok := r.categories[id]
+		return ok
+	}
+	return r.lookupTranslations(models.EntityCategory, live, locales), nil
+}
+
+// inCategory tells whether the ad sits anywhere below the live category.
+// The caller must hold the lock.
+func (s *store) inCategory(ad models.Advertisement, categoryID uint) bool {
+	category, ok := s.categories[categoryID]
+	return ok && category.Contains(s.categories[s.subcategories[ad.Subcategory_id].CategoryID])
+}
+
+// countAds counts the live ads of the subcategory. The caller must hold
+// the lock.
+func (s *store) countAds(subcategoryID uint) int64 {
+	var count int64
+	for _, ad := range s.ads {
+		if ad.Subcategory_id == subcategoryID {
+			count++
+		}
+	}
+	return count
+}
+
+// deleteAds deletes the live ads matching the predicate and returns them
+// as they were, by ID. The caller must hold the write lock.
+func (s *store) deleteAds(match func(models.Advertisement) bool, now gorm.DeletedAt) []models.AdDetails {
+	var ads []models.AdDetails
+	for id, ad := range s.ads {
+		if match(ad) {
+			ads = append(ads, s.details(ad))
+			ad.DeletedAt = now
+			delete(s.ads, id)
+			s.deletedAds[id] = ad
+		}
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].ID < ads[j].ID })
+	return ads
+}
+
+// restoreAds brings back the ads of the subcategory that were deleted at
+// the given time, unless their owner is deleted. The caller must hold the
+// write lock.
+func (s *store) restoreAds(subcategoryID uint, deletedAt time.Time) {
+	for id, ad := range s.deletedAds {
+		if _, ok := s.users[ad.User_id]; !ok {
+			continue
+		}
+		if ad.Subcategory_id == subcategoryID && ad.DeletedAt.Time.Equal(deletedAt) {
+			ad.DeletedAt = gorm.DeletedAt{}
+			delete(s.deletedAds, id)
+			s.ads[id] = ad
+		}
+	}
+}
+
+type subcategoryRepository struct {
+	*store
+}
+
+func (s *store) subcategory(subcategory models.Subcategory) models.Subcategory {
+	subcategory.Category = s.categories[subcategory.CategoryID]
+	subcategory.Translations = s.translationsOf(models.EntitySubcategory, subcategory.ID)
+	return subcategory
+}
+
+func (r *subcategoryRepository) List(ctx context.Context) ([]models.Subcategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subcategories := make([]models.Subcategory, 0, len(r.subcategories))
+	for _, subcategory := range r.subcategories {
+		subcategories = append(subcategories, r.subcategory(subcategory))
+	}
+	sort.Slice(subcategories, func(i, j int) bool { return subcategories[i].ID < subcategories[j].ID })
+
+	return subcategories, nil
+}
+
+func (r *subcategoryRepository) Get(ctx context.Context, id uint) (models.Subcategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subcategory, ok := r.subcategories[id]
+	if !ok {
+		return models.Subcategory{}, repository.ErrNotFound
+	}
+	return r.subcategory(subcategory), nil
+}
+
+func (r *subcategoryRepository) GetByName(ctx context.Context, name string) (models.Subcategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Match the lowest ID like the SQL implementation's First.
+	var found *models.Subcategory
+	for _, subcategory := range r.subcategories {
+		if subcategory.Name == name && (found == nil || subcategory.ID < found.ID) {
+			found = &subcategory
+		}
+	}
+	if found == nil {
+		return models.Subcategory{}, repository.ErrNotFound
+	}
+	return r.subcategory(*found), nil
+}
+
+// checkSubcategory enforces the category foreign key and the unique
+// (category_id, name) index, which covers live subcategories only.
+func (s *store) checkSubcategory(subcategory *models.Subcategory) error {
+	if _, ok := s.categories[subcategory.CategoryID]; !ok {
+		return repository.ErrForeignKey
+	}
+	for _, other := range s.subcategories {
+		if other.ID != subcategory.ID && other.CategoryID == subcategory.CategoryID && other.Name == subcategory.Name {
+			return repository.ErrDuplicate
+		}
+	}
+	return nil
+}
+
+func (r *subcategoryRepository) Create(ctx context.Context, subcategory *models.Subcategory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkSubcategory(subcategory); err != nil {
+		return err
+	}
+
+	subcategory.ID = r.nextID("subcategories")
+	stored := *subcategory
+	stored.Category = models.Category{}
+	stored.Translations = nil
+	r.subcategories[subcategory.ID] = stored
+	r.saveTranslations(models.EntitySubcategory, subcategory.ID, subcategory.Translations)
+	subcategory.Translations = r.translationsOf(models.EntitySubcategory, subcategory.ID)
+	return nil
+}
+
+func (r *subcategoryRepository) Update(ctx context.Context, subcategory *models.Subcategory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.subcategories[subcategory.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkSubcategory(subcategory); err != nil {
+		return err
+	}
+
+	stored := *subcategory
+	stored.Category = models.Category{}
+	stored.Translations = nil
+	r.subcategories[subcategory.ID] = stored
+	r.saveTranslations(models.EntitySubcategory, subcategory.ID, subcategory.Translations)
+	subcategory.Translations = r.translationsOf(models.EntitySubcategory, subcategory.ID)
+	return nil
+}
+
+func (r *subcategoryRepository) Dependents(ctx context.Context, id uint) (repository.Dependents, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return repository.Dependents{Ads: r.countAds(id)}, nil
+}
+
+func (r *subcategoryRepository) Delete(ctx context.Context, id uint, opts repository.DeleteOptions) (repository.Deleted, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted repository.Deleted
+	subcategory, ok := r.subcategories[id]
+	if !ok {
+		return deleted, repository.ErrNotFound
+	}
+
+	now := deletedAt(time.Now())
+	switch {
+	case opts.ReassignTo != 0:
+		if _, ok := r.subcategories[opts.ReassignTo]; !ok {
+			if _, ok := r.deletedSubcategories[opts.ReassignTo]; !ok {
+				return deleted, repository.ErrForeignKey
+			}
+		}
+		for adID, ad := range r.ads {
+			if ad.Subcategory_id == id {
+				ad.Subcategory_id = opts.ReassignTo
+				r.ads[adID] = ad
+			}
+		}
+	case opts.Cascade:
+		deleted.Ads = r.deleteAds(func(ad models.Advertisement) bool {
+			return ad.Subcategory_id == id
+		}, now)
+	default:
+		if r.countAds(id) > 0 {
+			return deleted, repository.ErrForeignKey
+		}
+	}
+
+	subcategory.DeletedAt = now
+	delete(r.subcategories, id)
+	r.deletedSubcategories[id] = subcategory
+	return deleted, nil
+}
+
+func (r *subcategoryRepository) Restore(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subcategory, ok := r.deletedSubcategories[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkSubcategory(&subcategory); err != nil {
+		return err
+	}
+
+	r.restoreAds(id, subcategory.DeletedAt.Time)
+	subcategory.DeletedAt = gorm.DeletedAt{}
+	delete(r.deletedSubcategories, id)
+	r.subcategories[id] = subcategory
+	return nil
+}
+
+func (r *subcategoryRepository) Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	live := func(id uint) bool {
+		_, ok := r.subcategories[id]
+		return ok
+	}
+	return r.lookupTranslations(models.EntitySubcategory, live, locales), nil
+}
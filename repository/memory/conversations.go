@@ -0,0 +1,162 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type conversationRepository struct {
+	*store
+}
+
+// adTitle returns the title of the ad, deleted or not, as conversations
+// outlive the ads they are about.
+func (s *store) adTitle(id uint) string {
+	if ad, ok := s.ads[id]; ok {
+		return ad.Title
+	}
+	return s.deletedAds[id].Title
+}
+
+func (r *conversationRepository) Start(ctx context.Context, adID, buyerID, sellerID uint) (models.Conversation, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, conversation := range r.conversations {
+		if conversation.AdID == adID && conversation.BuyerID == buyerID {
+			return conversation, false, nil
+		}
+	}
+
+	if _, ok := r.ads[adID]; !ok {
+		return models.Conversation{}, false, repository.ErrForeignKey
+	}
+	for _, id := range []uint{buyerID, sellerID} {
+		if _, ok := r.users[id]; !ok {
+			return models.Conversation{}, false, repository.ErrForeignKey
+		}
+	}
+
+	conversation := models.Conversation{
+		ID:        r.nextID("conversations"),
+		AdID:      adID,
+		BuyerID:   buyerID,
+		SellerID:  sellerID,
+		CreatedAt: time.Now(),
+	}
+	r.conversations[conversation.ID] = conversation
+	return conversation, true, nil
+}
+
+func (r *conversationRepository) Get(ctx context.Context, id uint) (models.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return models.Conversation{}, repository.ErrNotFound
+	}
+	return conversation, nil
+}
+
+func (r *conversationRepository) ListForUser(ctx context.Context, userID uint) ([]models.ConversationSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var conversations []models.ConversationSummary
+	for _, conversation := range r.conversations {
+		if !conversation.HasParticipant(userID) {
+			continue
+		}
+
+		summary := models.ConversationSummary{
+			Conversation: conversation,
+			AdTitle:      r.adTitle(conversation.AdID),
+		}
+		for _, message := range r.messages {
+			if message.ConversationID == conversation.ID && message.SenderID != userID && message.ReadAt == nil {
+				summary.Unread++
+			}
+		}
+		conversations = append(conversations, summary)
+	}
+
+	lastActive := func(c models.ConversationSummary) time.Time {
+		if c.LastMessageAt != nil {
+			return *c.LastMessageAt
+		}
+		return c.CreatedAt
+	}
+	sort.Slice(conversations, func(i, j int) bool {
+		a, b := lastActive(conversations[i]), lastActive(conversations[j])
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return conversations[i].ID > conversations[j].ID
+	})
+
+	return conversations, nil
+}
+
+func (r *conversationRepository) Messages(ctx context.Context, conversationID, afterID uint, limit int) ([]models.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var messages []models.Message
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID && message.ID > afterID {
+			messages = append(messages, message)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return messages, nil
+}
+
+func (r *conversationRepository) AddMessage(ctx context.Context, message *models.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[message.ConversationID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := r.users[message.SenderID]; !ok {
+		return repository.ErrForeignKey
+	}
+
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	message.ID = r.nextID("messages")
+	r.messages[message.ID] = *message
+
+	createdAt := message.CreatedAt
+	conversation.LastMessageAt = &createdAt
+	r.conversations[conversation.ID] = conversation
+	return nil
+}
+
+func (r *conversationRepository) MarkRead(ctx context.Context, conversationID, readerID uint) ([]models.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var read []models.Message
+	for id, message := range r.messages {
+		if message.ConversationID == conversationID && message.SenderID != readerID && message.ReadAt == nil {
+			message.ReadAt = &now
+			r.messages[id] = message
+			read = append(read, message)
+		}
+	}
+	sort.Slice(read, func(i, j int) bool { return read[i].ID < read[j].ID })
+	return read, nil
+}
@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type favoriteKey struct {
+	userID uint
+	adID   uint
+}
+
+type favoriteRepository struct {
+	*store
+}
+
+func (r *favoriteRepository) Add(ctx context.Context, userID, adID uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return false, repository.ErrForeignKey
+	}
+	if _, ok := r.ads[adID]; !ok {
+		return false, repository.ErrForeignKey
+	}
+
+	key := favoriteKey{userID, adID}
+	if _, ok := r.favorites[key]; ok {
+		return false, nil
+	}
+	r.favorites[key] = time.Now()
+	return true, nil
+}
+
+func (r *favoriteRepository) Remove(ctx context.Context, userID, adID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := favoriteKey{userID, adID}
+	if _, ok := r.favorites[key]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.favorites, key)
+	return nil
+}
+
+func (r *favoriteRepository) List(ctx context.Context, userID uint, limit, offset int) (repository.AdPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var keys []favoriteKey
+	for key := range r.favorites {
+		ad, ok := r.ads[key.adID]
+		if !ok || key.userID != userID {
+			continue
+		}
+		if models.IsPrivateStatus(ad.Status) && ad.User_id != userID {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := r.favorites[keys[i]], r.favorites[keys[j]]
+		if !a.Equal(b) {
+			return a.After(b)
+		}
+		return keys[i].adID < keys[j].adID
+	})
+
+	ads := make([]models.AdDetails, len(keys))
+	for i, key := range keys {
+		ads[i] = r.details(r.ads[key.adID])
+	}
+
+	return page(ads, len(ads), limit, offset), nil
+}
+
+func (r *favoriteRepository) Contains(ctx context.Context, userID uint, adIDs []uint) (map[uint]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	contains := make(map[uint]bool)
+	for _, id := range adIDs {
+		if _, ok := r.favorites[favoriteKey{userID, id}]; ok {
+			contains[id] = true
+		}
+	}
+	return contains, nil
+}
+
+func (r *favoriteRepository) Users(ctx context.Context, adID uint) ([]uint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []uint
+	for key := range r.favorites {
+		if key.adID == adID {
+			ids = append(ids, key.userID)
+		}
+	}
+	return ids, nil
+}
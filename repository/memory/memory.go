@@ -0,0 +1,141 @@
+// Package memory implements the repositories in process. It mirrors the
+// constraints of the PostgreSQL schema closely enough for handler tests;
+// full-text search is approximated by case-insensitive word matching and
+// distances use a spherical Earth.
+package memory
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/encoding/ewkb"
+	"github.com/paulmach/orb/geojson"
+	"github.com/sciphilib/go-dacha/common"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type store struct {
+	mu sync.RWMutex
+
+	users         map[uint]models.User
+	categories    map[uint]models.Category
+	subcategories map[uint]models.Subcategory
+	ads           map[uint]models.Advertisement
+	refreshTokens map[uint]models.RefreshToken
+	favorites     map[favoriteKey]time.Time
+	conversations map[uint]models.Conversation
+	messages      map[uint]models.Message
+	savedSearches map[uint]models.SavedSearch
+	notifications map[uint]models.Notification
+	// translations holds the translations table by entity and ID.
+	translations      map[string]map[uint]models.Translations
+	reports           map[uint]models.Report
+	moderationActions map[uint]models.ModerationAction
+	auditEntries      map[uint]models.AuditEntry
+
+	// Soft-deleted records are moved out of the live maps above so that
+	// reads skip them, and back by the Restore methods.
+	deletedUsers         map[uint]models.User
+	deletedCategories    map[uint]models.Category
+	deletedSubcategories map[uint]models.Subcategory
+	deletedAds           map[uint]models.Advertisement
+
+	lastID map[string]uint
+}
+
+func New() repository.Repositories {
+	s := &store{
+		users:             make(map[uint]models.User),
+		categories:        make(map[uint]models.Category),
+		subcategories:     make(map[uint]models.Subcategory),
+		ads:               make(map[uint]models.Advertisement),
+		refreshTokens:     make(map[uint]models.RefreshToken),
+		favorites:         make(map[favoriteKey]time.Time),
+		conversations:     make(map[uint]models.Conversation),
+		messages:          make(map[uint]models.Message),
+		savedSearches:     make(map[uint]models.SavedSearch),
+		notifications:     make(map[uint]models.Notification),
+		translations:      make(map[string]map[uint]models.Translations),
+		reports:           make(map[uint]models.Report),
+		moderationActions: make(map[uint]models.ModerationAction),
+		auditEntries:      make(map[uint]models.AuditEntry),
+
+		deletedUsers:         make(map[uint]models.User),
+		deletedCategories:    make(map[uint]models.Category),
+		deletedSubcategories: make(map[uint]models.Subcategory),
+		deletedAds:           make(map[uint]models.Advertisement),
+
+		lastID: make(map[string]uint),
+	}
+
+	return repository.Repositories{
+		Ads:           &adRepository{s},
+		Users:         &userRepository{s},
+		Categories:    &categoryRepository{s},
+		Subcategories: &subcategoryRepository{s},
+		RefreshTokens: &refreshTokenRepository{s},
+		Favorites:     &favoriteRepository{s},
+		Conversations: &conversationRepository{s},
+		SavedSearches: &savedSearchRepository{s},
+		Notifications: &notificationRepository{s},
+		Moderation:    &moderationRepository{s},
+		Audit:         &auditRepository{s},
+	}
+}
+
+// nextID returns the next value of the table's ID sequence. The caller
+// must hold the write lock.
+func (s *store) nextID(table string) uint {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+func deletedAt(now time.Time) gorm.DeletedAt {
+	return gorm.DeletedAt{Time: now, Valid: true}
+}
+
+func point(location []byte) (orb.Point, bool) {
+	if len(location) == 0 {
+		return orb.Point{}, false
+	}
+	geom, _, err := ewkb.Unmarshal(location)
+	if err != nil {
+		return orb.Point{}, false
+	}
+	p, ok := geom.(orb.Point)
+	return p, ok
+}
+
+func locationJSON(location []byte) common.GeoJSONText {
+	empty := common.GeoJSONText{Data: json.RawMessage("{}")}
+	if len(location) == 0 {
+		return empty
+	}
+	geom, _, err := ewkb.Unmarshal(location)
+	if err != nil {
+		return empty
+	}
+	data, err := geojson.NewGeometry(geom).MarshalJSON()
+	if err != nil {
+		return empty
+	}
+	return common.GeoJSONText{Data: data}
+}
+
+const earthRadius = 6371008.8
+
+// distance returns the great-circle distance between two points in meters.
+func distance(a, b orb.Point) float64 {
+	lat1, lat2 := a.Lat()*math.Pi/180, b.Lat()*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Lon() - a.Lon()) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type moderationRepository struct {
+	*store
+}
+
+func (r *moderationRepository) Report(ctx context.Context, report *models.Report) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.ads[report.AdID]; !ok {
+		return repository.ErrForeignKey
+	}
+	if _, ok := r.users[report.UserID]; !ok {
+		return repository.ErrForeignKey
+	}
+	for _, other := range r.reports {
+		if other.AdID == report.AdID && other.UserID == report.UserID && other.ResolvedAt == nil {
+			return repository.ErrDuplicate
+		}
+	}
+
+	report.ID = r.nextID("reports")
+	report.CreatedAt = time.Now()
+	r.reports[report.ID] = *report
+	return nil
+}
+
+// openReports counts the open reports of each ad. The caller must hold
+// the lock.
+func (s *store) openReports() map[uint]int {
+	counts := make(map[uint]int)
+	for _, report := range s.reports {
+		if report.ResolvedAt == nil {
+			counts[report.AdID]++
+		}
+	}
+	return counts
+}
+
+func (r *moderationRepository) Queue(ctx context.Context, limit, offset int) (repository.AdPage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := r.openReports()
+	var queued []models.Advertisement
+	for _, ad := range r.ads {
+		if ad.Status != models.StatusDraft && (counts[ad.ID] > 0 || ad.ReviewedAt == nil) {
+			queued = append(queued, ad)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		a, b := counts[queued[i].ID], counts[queued[j].ID]
+		if a != b {
+			return a > b
+		}
+		return queued[i].ID < queued[j].ID
+	})
+
+	ads := make([]models.AdDetails, len(queued))
+	for i, ad := range queued {
+		ads[i] = r.details(ad)
+	}
+
+	return page(ads, len(ads), limit, offset), nil
+}
+
+func (r *moderationRepository) OpenReports(ctx context.Context, adIDs []uint) (map[uint][]models.Report, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[uint]bool, len(adIDs))
+	for _, id := range adIDs {
+		wanted[id] = true
+	}
+
+	reports := make(map[uint][]models.Report)
+	for _, report := range r.reports {
+		if wanted[report.AdID] && report.ResolvedAt == nil {
+			reports[report.AdID] = append(reports[report.AdID], report)
+		}
+	}
+	for _, list := range reports {
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	}
+	return reports, nil
+}
+
+func (r *moderationRepository) Act(ctx context.Context, action *models.ModerationAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ad, ok := r.ads[action.AdID]
+	if !ok || ad.Status != action.FromStatus {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	ad.Status = action.ToStatus
+	ad.ReviewedAt = &now
+	if ad.Status == models.StatusActive {
+		ad.MatchedAt = nil
+	}
+	r.ads[ad.ID] = ad
+
+	action.ID = r.nextID("moderation_actions")
+	action.CreatedAt = now
+	r.moderationActions[action.ID] = *action
+
+	for id, report := range r.reports {
+		if report.AdID == ad.ID && report.ResolvedAt == nil {
+			report.ResolvedAt = &now
+			report.ActionID = &action.ID
+			r.reports[id] = report
+		}
+	}
+	return nil
+}
+
+func (r *moderationRepository) Actions(ctx context.Context, adID uint) ([]models.ModerationAction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var actions []models.ModerationAction
+	for _, action := range r.moderationActions {
+		if action.AdID == adID {
+			actions = append(actions, action)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool { return actions[i].ID > actions[j].ID })
+	return actions, nil
+}
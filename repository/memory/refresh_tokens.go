@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type refreshTokenRepository struct {
+	*store
+}
+
+func (r *refreshTokenRepository) create(token *models.RefreshToken) error {
+	if _, ok := r.users[token.UserID]; !ok {
+		return repository.ErrForeignKey
+	}
+	for _, other := range r.refreshTokens {
+		if other.TokenHash == token.TokenHash {
+			return repository.ErrDuplicate
+		}
+	}
+
+	token.ID = r.nextID("refresh_tokens")
+	token.CreatedAt = time.Now()
+	r.refreshTokens[token.ID] = *token
+	return nil
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.create(token)
+}
+
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, hash string) (models.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.refreshTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, repository.ErrNotFound
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, id uint, next *models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.refreshTokens[id]
+	if !ok || token.RevokedAt != nil {
+		return repository.ErrNotFound
+	}
+
+	if err := r.create(next); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	token.RevokedAt = &now
+	r.refreshTokens[id] = token
+	return nil
+}
+
+func (r *refreshTokenRepository) Revoke(ctx context.Context, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.refreshTokens {
+		if token.TokenHash == hash && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.refreshTokens[id] = token
+		}
+	}
+	return nil
+}
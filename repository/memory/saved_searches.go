@@ -0,0 +1,235 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+type savedSearchRepository struct {
+	*store
+}
+
+// deleteSavedSearches deletes the matching saved searches with their
+// notifications. The caller must hold the write lock.
+func (s *store) deleteSavedSearches(match func(models.SavedSearch) bool) {
+	for id, search := range s.savedSearches {
+		if !match(search) {
+			continue
+		}
+		delete(s.savedSearches, id)
+		for notificationID, notification := range s.notifications {
+			if notification.SavedSearchID == id {
+				delete(s.notifications, notificationID)
+			}
+		}
+	}
+}
+
+func (s *store) checkSavedSearch(search *models.SavedSearch) error {
+	if _, ok := s.users[search.UserID]; !ok {
+		return repository.ErrForeignKey
+	}
+	if search.CategoryID != nil {
+		if _, ok := s.categories[*search.CategoryID]; !ok {
+			return repository.ErrForeignKey
+		}
+	}
+	if search.SubcategoryID != nil {
+		if _, ok := s.subcategories[*search.SubcategoryID]; !ok {
+			return repository.ErrForeignKey
+		}
+	}
+	return nil
+}
+
+func (r *savedSearchRepository) List(ctx context.Context, userID uint) ([]models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var searches []models.SavedSearch
+	for _, search := range r.savedSearches {
+		if search.UserID == userID {
+			searches = append(searches, search)
+		}
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].ID < searches[j].ID })
+	return searches, nil
+}
+
+func (r *savedSearchRepository) Get(ctx context.Context, id uint) (models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search, ok := r.savedSearches[id]
+	if !ok {
+		return models.SavedSearch{}, repository.ErrNotFound
+	}
+	return search, nil
+}
+
+func (r *savedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkSavedSearch(search); err != nil {
+		return err
+	}
+
+	search.ID = r.nextID("saved_searches")
+	if search.CreatedAt.IsZero() {
+		search.CreatedAt = time.Now()
+	}
+	r.savedSearches[search.ID] = *search
+	return nil
+}
+
+func (r *savedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.savedSearches[search.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkSavedSearch(search); err != nil {
+		return err
+	}
+
+	r.savedSearches[search.ID] = *search
+	return nil
+}
+
+func (r *savedSearchRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.savedSearches[id]; !ok {
+		return repository.ErrNotFound
+	}
+	r.deleteSavedSearches(func(search models.SavedSearch) bool { return search.ID == id })
+	return nil
+}
+
+// filters translates a saved search into the filters of an ad search.
+func filters(search models.SavedSearch) (repository.AdFilter, repository.GeoFilter) {
+	filter := repository.AdFilter{
+		PriceMin: search.PriceMin,
+		PriceMax: search.PriceMax,
+		Currency: search.Currency,
+		Query:    search.Query,
+	}
+	if search.CategoryID != nil {
+		filter.CategoryID = *search.CategoryID
+	}
+	if search.SubcategoryID != nil {
+		filter.SubcategoryID = *search.SubcategoryID
+	}
+
+	var geo repository.GeoFilter
+	if search.Radius != nil {
+		geo = repository.GeoFilter{Lat: *search.Lat, Lon: *search.Lon, Radius: *search.Radius}
+	}
+
+	return filter, geo
+}
+
+func (r *savedSearchRepository) Matching(ctx context.Context, adID uint) ([]models.SavedSearch, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ad, ok := r.ads[adID]
+	if !ok || ad.Status != models.StatusActive {
+		return nil, nil
+	}
+	location, hasLocation := point(ad.LocationEWKB)
+
+	var searches []models.SavedSearch
+	for _, search := range r.savedSearches {
+		if _, ok := r.users[search.UserID]; !ok || search.UserID == ad.User_id {
+			continue
+		}
+		filter, geo := filters(search)
+		if !r.matches(ad, filter) {
+			continue
+		}
+		if geo.Radius != 0 && (!hasLocation || !matchesGeo(location, geo)) {
+			continue
+		}
+		searches = append(searches, search)
+	}
+	sort.Slice(searches, func(i, j int) bool { return searches[i].ID < searches[j].ID })
+	return searches, nil
+}
+
+type notificationRepository struct {
+	*store
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[notification.UserID]; !ok {
+		return repository.ErrForeignKey
+	}
+	if _, ok := r.ads[notification.AdID]; !ok {
+		return repository.ErrForeignKey
+	}
+	if _, ok := r.savedSearches[notification.SavedSearchID]; !ok {
+		return repository.ErrForeignKey
+	}
+	for _, existing := range r.notifications {
+		if existing.SavedSearchID == notification.SavedSearchID && existing.AdID == notification.AdID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	notification.ID = r.nextID("notifications")
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+	r.notifications[notification.ID] = *notification
+	return nil
+}
+
+func (r *notificationRepository) List(ctx context.Context, userID uint, limit, offset int) ([]models.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var notifications []models.Notification
+	for _, notification := range r.notifications {
+		if notification.UserID == userID {
+			notifications = append(notifications, notification)
+		}
+	}
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+
+	if offset >= len(notifications) {
+		return nil, nil
+	}
+	notifications = notifications[offset:]
+	if len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id uint) (models.Notification, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	notification, ok := r.notifications[id]
+	if !ok || notification.UserID != userID {
+		return models.Notification{}, false, repository.ErrNotFound
+	}
+	if notification.ReadAt != nil {
+		return notification, false, nil
+	}
+	now := time.Now()
+	notification.ReadAt = &now
+	r.notifications[id] = notification
+	return notification, true, nil
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"maps"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+// translationsOf returns a copy of the translations of the entity. The
+// caller must hold the lock.
+func (s *store) translationsOf(entity string, id uint) models.Translations {
+	translations := maps.Clone(s.translations[entity][id])
+	if translations == nil {
+		translations = models.Translations{}
+	}
+	return translations
+}
+
+// saveTranslations replaces the translations of the entity. The caller
+// must hold the write lock.
+func (s *store) saveTranslations(entity string, id uint, translations models.Translations) {
+	if s.translations[entity] == nil {
+		s.translations[entity] = make(map[uint]models.Translations)
+	}
+	s.translations[entity][id] = maps.Clone(translations)
+}
+
+// lookupTranslations returns the translations into the locales of the
+// entities for which live is true. The caller must hold the lock.
+func (s *store) lookupTranslations(entity string, live func(id uint) bool, locales []string) map[uint]models.Translations {
+	result := make(map[uint]models.Translations)
+	for id, translations := range s.translations[entity] {
+		if !live(id) {
+			continue
+		}
+		for _, locale := range locales {
+			if name, ok := translations[locale]; ok {
+				if result[id] == nil {
+					result[id] = make(models.Translations)
+				}
+				result[id][locale] = name
+			}
+		}
+	}
+	return result
+}
+
+// category fills in the translations of a stored category.
+func (s *store) category(category models.Category) models.Category {
+	category.Translations = s.translationsOf(models.EntityCategory, category.ID)
+	return category
+}
@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	*store
+}
+
+func (s *store) user(user models.User) models.User {
+	user.LocationText = locationJSON(user.LocationEWKB)
+	return user
+}
+
+// checkUnique enforces the unique email and phone number indexes, which
+// cover live users only.
+func (s *store) checkUnique(user *models.User) error {
+	for _, other := range s.users {
+		if other.ID != user.ID && (other.Email == user.Email || other.PhoneNumber == user.PhoneNumber) {
+			return repository.ErrDuplicate
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, r.user(user))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	return users, nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, repository.ErrNotFound
+	}
+	return r.user(user), nil
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email {
+			return r.user(user), nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+
+	user.ID = r.nextID("users")
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user.Role = role
+	r.users[id] = user
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uint) (repository.Deleted, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted repository.Deleted
+	user, ok := r.users[id]
+	if !ok {
+		return deleted, repository.ErrNotFound
+	}
+
+	now := deletedAt(time.Now())
+	deleted.Ads = r.deleteAds(func(ad models.Advertisement) bool {
+		return ad.User_id == id
+	}, now)
+	user.DeletedAt = now
+	delete(r.users, id)
+	r.deletedUsers[id] = user
+	for tokenID, token := range r.refreshTokens {
+		if token.UserID == id {
+			delete(r.refreshTokens, tokenID)
+		}
+	}
+	return deleted, nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.deletedUsers[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := r.checkUnique(&user); err != nil {
+		return err
+	}
+
+	for adID, ad := range r.deletedAds {
+		if _, ok := r.subcategories[ad.Subcategory_id]; !ok {
+			continue
+		}
+		if ad.User_id == id && ad.DeletedAt.Time.Equal(user.DeletedAt.Time) {
+			ad.DeletedAt = gorm.DeletedAt{}
+			delete(r.deletedAds, adID)
+			r.ads[adID] = ad
+		}
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	delete(r.deletedUsers, id)
+	r.users[id] = user
+	return nil
+}
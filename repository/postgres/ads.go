@@ -0,0 +1,425 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type adRepository struct {
+	db *gorm.DB
+}
+
+// adRow is an advertisement joined with its subcategory, category and
+// owner.
+type adRow struct {
+	models.Advertisement
+	SubcategoryName  string
+	CategoryID       uint
+	CategoryName     string
+	LocationText     sql.NullString
+	Pictures         pq.StringArray `gorm:"column:pictures"`
+	UserName         string
+	UserEmail        string
+	UserPhoneNumber  string
+	UserRole         string
+	UserLocationText sql.NullString
+	Distance         *float64
+}
+
+const adColumns = `
+	advertisements.*,
+	subcategories.name AS subcategory_name,
+	subcategories.category_id AS category_id,
+	categories.name AS category_name,
+	ST_AsGeoJSON(advertisements.location::geometry) AS location_text,
+	users.name AS user_name,
+	users.email AS user_email,
+	users.phone_number AS user_phone_number,
+	users.role AS user_role,
+	ST_AsGeoJSON(users.location::geometry) AS user_location_text`
+
+// tsQuerySQL matches the search text, bound twice, with both the Russian
+// and the English stemmer since listings are written in either language.
+const tsQuerySQL = `(websearch_to_tsquery('russian', ?) || websearch_to_tsquery('english', ?))`
+
+// originSQL is the point distances are measured from, bound to lon and lat.
+const originSQL = `ST_SetSRID(ST_MakePoint(?, ?), 4326)::geography`
+
+func (row adRow) details() models.AdDetails {
+	ad := row.Advertisement
+	ad.Subcategory = models.SubcategoryWithCategory{
+		ID:         ad.Subcategory_id,
+		Name:       row.SubcategoryName,
+		CategoryID: row.CategoryID,
+		Category:   row.CategoryName,
+	}
+	ad.LocationText = geoJSON(row.LocationText)
+	ad.Pictures = row.Pictures
+	ad.PicturesText = make([]string, len(row.Pictures))
+	copy(ad.PicturesText, row.Pictures)
+
+	return models.AdDetails{
+		Advertisement: ad,
+		User: models.User{
+			ID:           ad.User_id,
+			Name:         row.UserName,
+			Email:        row.UserEmail,
+			PhoneNumber:  row.UserPhoneNumber,
+			Role:         row.UserRole,
+			LocationText: geoJSON(row.UserLocationText),
+		},
+		Distance: row.Distance,
+	}
+}
+
+func detailsOf(rows []adRow) []models.AdDetails {
+	ads := make([]models.AdDetails, len(rows))
+	for i, row := range rows {
+		ads[i] = row.details()
+	}
+	return ads
+}
+
+func (r *adRepository) query(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).
+		Table("advertisements").
+		Joins("JOIN subcategories ON subcategories.id = advertisements.subcategory_id").
+		Joins("JOIN categories ON categories.id = subcategories.category_id").
+		Joins("JOIN users ON users.id = advertisements.user_id").
+		Where("advertisements.deleted_at IS NULL")
+}
+
+func applyFilter(query *gorm.DB, f repository.AdFilter) *gorm.DB {
+	if f.CategoryID != 0 {
+		query = query.Where("categories.path LIKE (SELECT path || '%' FROM categories WHERE id = ?)", f.CategoryID)
+	}
+	if f.SubcategoryID != 0 {
+		query = query.Where("advertisements.subcategory_id = ?", f.SubcategoryID)
+	}
+	if f.PriceMin != nil {
+		query = query.Where("advertisements.price_amount >= ? AND NOT advertisements.price_free", *f.PriceMin)
+	}
+	if f.PriceMax != nil {
+		query = query.Where("(advertisements.price_amount <= ? OR advertisements.price_free)", *f.PriceMax)
+	}
+	if f.Currency != "" {
+		query = query.Where("(advertisements.price_currency = ? OR advertisements.price_free)", f.Currency)
+	}
+	if f.From != nil {
+		query = query.Where("advertisements.datetime >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("advertisements.datetime <= ?", *f.To)
+	}
+	if f.UserID != 0 {
+		query = query.Where("advertisements.user_id = ?", f.UserID)
+	}
+	if f.Query != "" {
+		query = query.Where("advertisements.search_vector @@ "+tsQuerySQL, f.Query, f.Query)
+	}
+	if f.Status != "" {
+		query = query.Where("advertisements.status = ?", f.Status)
+	}
+	for _, attr := range f.Attributes {
+		query = applyAttributeFilter(query, attr)
+	}
+
+	return query
+}
+
+// attributeNumberSQL is the numeric value of the attribute bound to its
+// name twice, or NULL when the attribute is not a number.
+const attributeNumberSQL = `CASE WHEN jsonb_typeof(advertisements.attributes -> ?) = 'number'
+	THEN (advertisements.attributes ->> ?)::numeric END`
+
+func applyAttributeFilter(query *gorm.DB, f repository.AttributeFilter) *gorm.DB {
+	if f.Value != "" {
+		query = query.Where("advertisements.attributes ->> ? = ?", f.Name, f.Value)
+	}
+	if f.Min != nil {
+		query = query.Where(attributeNumberSQL+" >= ?", f.Name, f.Name, *f.Min)
+	}
+	if f.Max != nil {
+		query = query.Where(attributeNumberSQL+" <= ?", f.Name, f.Name, *f.Max)
+	}
+	return query
+}
+
+func applyGeoFilter(query *gorm.DB, f repository.GeoFilter) *gorm.DB {
+	if f.Radius != 0 {
+		query = query.Where("ST_DWithin(advertisements.location, "+originSQL+", ?)", f.Lon, f.Lat, f.Radius)
+	}
+	if f.BBox != nil {
+		query = query.Where("advertisements.location::geometry && ST_MakeEnvelope(?, ?, ?, ?, 4326)",
+			f.BBox[0], f.BBox[1], f.BBox[2], f.BBox[3])
+	}
+
+	return query
+}
+
+// applyOrder sorts by price when asked to, then by relevance to the
+// search text, and by ID otherwise.
+func applyOrder(query *gorm.DB, f repository.AdFilter) *gorm.DB {
+	switch {
+	case f.Sort == repository.SortPriceAsc:
+		return query.Order("advertisements.price_free DESC, advertisements.price_amount, advertisements.id")
+	case f.Sort == repository.SortPriceDesc:
+		return query.Order("advertisements.price_free, advertisements.price_amount DESC, advertisements.id")
+	case f.Query != "":
+		return query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(advertisements.search_vector, " + tsQuerySQL + ") DESC, advertisements.id",
+			Vars:               []interface{}{f.Query, f.Query},
+			WithoutParentheses: true,
+		}})
+	default:
+		return query.Order("advertisements.id")
+	}
+}
+
+// page counts the ads matched by count, then fetches one more than the
+// limit with fetch to tell whether another page follows.
+func page(count, fetch *gorm.DB, limit, offset int) (repository.AdPage, error) {
+	var result repository.AdPage
+	if err := count.Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	var rows []adRow
+	if err := fetch.Limit(limit + 1).Offset(offset).Scan(&rows).Error; err != nil {
+		return result, err
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+		result.HasMore = true
+	}
+	result.Ads = detailsOf(rows)
+
+	return result, nil
+}
+
+func (r *adRepository) List(ctx context.Context, filter repository.AdFilter) (repository.AdPage, error) {
+	query := applyFilter(r.query(ctx), filter).Session(&gorm.Session{})
+
+	fetch := query
+	if filter.Cursor != 0 {
+		fetch = fetch.Where("advertisements.id > ?", filter.Cursor)
+	}
+	fetch = applyOrder(fetch, filter).Select(adColumns)
+
+	return page(query, fetch, filter.Limit, filter.Offset)
+}
+
+func (r *adRepository) Search(ctx context.Context, filter repository.AdFilter, geo repository.GeoFilter) (repository.AdPage, error) {
+	query := applyGeoFilter(applyFilter(r.query(ctx), filter), geo).Session(&gorm.Session{})
+
+	fetch := query.
+		Select(adColumns+", ST_Distance(advertisements.location, "+originSQL+") AS distance", geo.Lon, geo.Lat).
+		Order("distance, advertisements.id")
+
+	return page(query, fetch, filter.Limit, filter.Offset)
+}
+
+func (r *adRepository) Newest(ctx context.Context) ([]models.AdDetails, error) {
+	var rows []adRow
+	err := r.query(ctx).
+		Select(adColumns).
+		Where("advertisements.status = ?", models.StatusActive).
+		Order("advertisements.datetime DESC").
+		Scan(&rows).Error
+	return detailsOf(rows), err
+}
+
+func (r *adRepository) NearestTo(ctx context.Context, userID uint) ([]models.AdDetails, error) {
+	var rows []adRow
+	err := r.query(ctx).
+		Select(adColumns+`, ST_Distance(
+			advertisements.location,
+			(SELECT location FROM users WHERE id = ?)
+		) AS distance`, userID).
+		Where("advertisements.status = ?", models.StatusActive).
+		Order("distance ASC NULLS LAST").
+		Scan(&rows).Error
+	return detailsOf(rows), err
+}
+
+func (r *adRepository) GetDetails(ctx context.Context, id uint) (models.AdDetails, error) {
+	var row adRow
+	err := r.query(ctx).
+		Select(adColumns).
+		Where("advertisements.id = ?", id).
+		Take(&row).Error
+	if err != nil {
+		return models.AdDetails{}, translate(err)
+	}
+	return row.details(), nil
+}
+
+func (r *adRepository) Get(ctx context.Context, id uint) (models.Advertisement, error) {
+	var ad models.Advertisement
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&ad).Error
+	return ad, translate(err)
+}
+
+func (r *adRepository) Create(ctx context.Context, ad *models.Advertisement) error {
+	return translate(r.db.WithContext(ctx).Create(ad).Error)
+}
+
+func (r *adRepository) Update(ctx context.Context, ad *models.Advertisement) error {
+	return translate(r.db.WithContext(ctx).Omit("Pictures", "Status", "ExpiresAt", "ReviewedAt", "MatchedAt").Save(ad).Error)
+}
+
+func (r *adRepository) SetStatus(ctx context.Context, id uint, from, to string) error {
+	updates := map[string]interface{}{"status": to}
+	if from == models.StatusRejected {
+		updates["reviewed_at"] = nil
+	}
+	if to == models.StatusActive {
+		updates["matched_at"] = nil
+	}
+	return affected(r.db.WithContext(ctx).
+		Model(&models.Advertisement{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(updates))
+}
+
+func (r *adRepository) Renew(ctx context.Context, id uint, until time.Time) error {
+	return affected(r.db.WithContext(ctx).
+		Model(&models.Advertisement{}).
+		Where("id = ? AND status IN ?", id, []string{models.StatusDraft, models.StatusActive, models.StatusExpired}).
+		Updates(map[string]interface{}{
+			"expires_at": until,
+			"status":     gorm.Expr("CASE WHEN status = ? THEN ? ELSE status END", models.StatusExpired, models.StatusActive),
+		}))
+}
+
+func (r *adRepository) Expire(ctx context.Context, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Raw("UPDATE advertisements SET status = ? WHERE status = ? AND expires_at <= ? AND deleted_at IS NULL RETURNING id",
+			models.StatusExpired, models.StatusActive, now).
+		Scan(&ids).Error
+	return ids, err
+}
+
+func (r *adRepository) Delete(ctx context.Context, id uint) error {
+	return affected(r.db.WithContext(ctx).Delete(&models.Advertisement{}, id))
+}
+
+func (r *adRepository) Unmatched(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&models.Advertisement{}).
+		Where("matched_at IS NULL AND status = ?", models.StatusActive).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *adRepository) MarkMatched(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).
+		Model(&models.Advertisement{}).
+		Where("id = ?", id).
+		Update("matched_at", time.Now()).Error
+}
+
+// deleteAds deletes the live ads matching the condition and returns them
+// as they were.
+func deleteAds(tx *gorm.DB, now time.Time, query string, args ...interface{}) ([]models.AdDetails, error) {
+	var rows []adRow
+	err := (&adRepository{db: tx}).query(tx.Statement.Context).
+		Select(adColumns).
+		Where(query, args...).
+		Order("advertisements.id").
+		Find(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	err = tx.Model(&models.Advertisement{}).Where("id IN ?", ids).Update("deleted_at", now).Error
+	return detailsOf(rows), err
+}
+
+func (r *adRepository) Restore(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ad models.Advertisement
+		err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&ad).Error
+		if err != nil {
+			return translate(err)
+		}
+
+		if err := requireLive(tx, "subcategories", ad.Subcategory_id); err != nil {
+			return err
+		}
+		if err := requireLive(tx, "users", ad.User_id); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&ad).Update("deleted_at", nil).Error
+	})
+}
+
+// updatePictures sets the pictures column to expr, whose arguments come
+// before the ad ID, in a single statement.
+func (r *adRepository) updatePictures(ctx context.Context, id uint, expr string, args ...interface{}) ([]string, error) {
+	var row struct {
+		Pictures pq.StringArray
+	}
+	result := r.db.WithContext(ctx).
+		Raw("UPDATE advertisements SET pictures = "+expr+" WHERE id = ? AND deleted_at IS NULL RETURNING pictures", append(args, id)...).
+		Scan(&row)
+	if result.Error != nil {
+		return nil, translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return row.Pictures, nil
+}
+
+// AppendPictures locks the ad row while it counts the pictures so that
+// concurrent uploads cannot together go over the limit.
+func (r *adRepository) AppendPictures(ctx context.Context, id uint, pictures []string, limit int) ([]string, error) {
+	var result []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var row struct {
+			Pictures pq.StringArray
+		}
+		locked := tx.Raw("SELECT pictures FROM advertisements WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id).Scan(&row)
+		if locked.Error != nil {
+			return translate(locked.Error)
+		}
+		if locked.RowsAffected == 0 {
+			return repository.ErrNotFound
+		}
+		if len(row.Pictures)+len(pictures) > limit {
+			return repository.ErrLimit
+		}
+
+		var err error
+		ads := &adRepository{db: tx}
+		result, err = ads.updatePictures(ctx, id, "array_cat(COALESCE(pictures, '{}'), ?::text[])", pq.StringArray(pictures))
+		return err
+	})
+	return result, err
+}
+
+func (r *adRepository) RemovePicture(ctx context.Context, id uint, picture string) ([]string, error) {
+	return r.updatePictures(ctx, id, "array_remove(pictures, ?)", picture)
+}
+
+func (r *adRepository) SetPictures(ctx context.Context, id uint, pictures []string) ([]string, error) {
+	return r.updatePictures(ctx, id, "?::text[]", pq.StringArray(pictures))
+}
@@ -0,0 +1,66 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditRepository) Record(ctx context.Context, entry *models.AuditEntry) error {
+	return translate(r.db.WithContext(ctx).Create(entry).Error)
+}
+
+func applyAuditFilter(query *gorm.DB, f repository.AuditFilter) *gorm.DB {
+	if f.ActorID != 0 {
+		query = query.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Entity != "" {
+		query = query.Where("entity = ?", f.Entity)
+	}
+	if f.EntityID != 0 {
+		query = query.Where("entity_id = ?", f.EntityID)
+	}
+	if f.Action != "" {
+		query = query.Where("action = ?", f.Action)
+	}
+	if f.RequestID != "" {
+		query = query.Where("request_id = ?", f.RequestID)
+	}
+	if f.From != nil {
+		query = query.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("created_at <= ?", *f.To)
+	}
+	return query
+}
+
+func (r *auditRepository) List(ctx context.Context, filter repository.AuditFilter) (repository.AuditPage, error) {
+	query := applyAuditFilter(r.db.WithContext(ctx).Model(&models.AuditEntry{}), filter).
+		Session(&gorm.Session{})
+
+	var result repository.AuditPage
+	if err := query.Count(&result.Total).Error; err != nil {
+		return result, err
+	}
+
+	var entries []models.AuditEntry
+	err := query.Order("id DESC").Limit(filter.Limit + 1).Offset(filter.Offset).Find(&entries).Error
+	if err != nil {
+		return result, err
+	}
+
+	if len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+		result.HasMore = true
+	}
+	result.Entries = entries
+
+	return result, nil
+}
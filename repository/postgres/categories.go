@@ -0,0 +1,411 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type categoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *categoryRepository) List(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	db := r.db.WithContext(ctx)
+	if err := db.Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, withCategoryTranslations(db, categories)
+}
+
+func (r *categoryRepository) Get(ctx context.Context, id uint) (models.Category, error) {
+	return r.get(r.db.WithContext(ctx), "id = ?", id)
+}
+
+func (r *categoryRepository) get(db *gorm.DB, query string, arg interface{}) (models.Category, error) {
+	var category models.Category
+	if err := db.Where(query, arg).First(&category).Error; err != nil {
+		return category, translate(err)
+	}
+
+	var err error
+	category.Translations, err = translationsOf(db, models.EntityCategory, category.ID)
+	return category, err
+}
+
+func (r *categoryRepository) Descendants(ctx context.Context, id uint) ([]models.Category, error) {
+	var categories []models.Category
+	db := r.db.WithContext(ctx)
+	err := db.
+		Where("path LIKE (SELECT path || '%' FROM categories WHERE id = ?) AND id <> ?", id, id).
+		Order("path").
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	return categories, withCategoryTranslations(db, categories)
+}
+
+func (r *categoryRepository) Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error) {
+	return lookupTranslations(r.db.WithContext(ctx), models.EntityCategory, "categories", locales)
+}
+
+// parentOf loads the live category with the given ID, or returns nil for
+// the root.
+func parentOf(tx *gorm.DB, id *uint) (*models.Category, error) {
+	if id == nil {
+		return nil, nil
+	}
+
+	var parent models.Category
+	err := tx.Where("id = ?", *id).First(&parent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, repository.ErrForeignKey
+	}
+	return &parent, err
+}
+
+// move puts the category under parent together with everything below it,
+// deleted or not. It returns ErrDuplicate when the parent has a child with
+// the same name.
+func move(tx *gorm.DB, category models.Category, parent *models.Category) error {
+	moved := category
+	moved.PlaceUnder(parent)
+
+	err := tx.Exec(`UPDATE categories SET path = ? || substr(path, ?) WHERE path LIKE ?`,
+		moved.Path, len(category.Path)+1, category.Path+"%").Error
+	if err != nil {
+		return err
+	}
+	return translate(tx.Exec(`UPDATE categories SET parent_id = ? WHERE id = ?`, moved.ParentID, category.ID).Error)
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parent, err := parentOf(tx, category.ParentID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(category).Error; err != nil {
+			return translate(err)
+		}
+		category.PlaceUnder(parent)
+		if err := tx.Model(category).Update("path", category.Path).Error; err != nil {
+			return err
+		}
+
+		category.Translations = orEmpty(category.Translations)
+		return saveTranslations(tx, models.EntityCategory, category.ID, category.Translations)
+	})
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current models.Category
+		if err := tx.Where("id = ?", category.ID).First(&current).Error; err != nil {
+			return translate(err)
+		}
+
+		if !current.SameParent(*category) {
+			parent, err := parentOf(tx, category.ParentID)
+			if err != nil {
+				return err
+			}
+			if err := move(tx, current, parent); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&current).Update("name", category.Name).Error; err != nil {
+			return translate(err)
+		}
+		category.Translations = orEmpty(category.Translations)
+		if err := saveTranslations(tx, models.EntityCategory, category.ID, category.Translations); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", category.ID).First(category).Error
+	})
+}
+
+func (r *categoryRepository) Dependents(ctx context.Context, id uint) (repository.Dependents, error) {
+	var dependents repository.Dependents
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT
+			(SELECT count(*) FROM categories
+			 WHERE parent_id = ? AND deleted_at IS NULL) AS categories,
+			(SELECT count(*) FROM subcategories
+			 WHERE category_id = ? AND deleted_at IS NULL) AS subcategories,
+			(SELECT count(*) FROM advertisements
+			 JOIN subcategories ON subcategories.id = advertisements.subcategory_id
+			 JOIN categories ON categories.id = subcategories.category_id
+			 WHERE categories.path LIKE (SELECT path || '%' FROM categories WHERE id = ?)
+			   AND advertisements.deleted_at IS NULL) AS ads`, id, id, id).
+		Scan(&dependents).Error
+	return dependents, err
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, id uint, opts repository.DeleteOptions) (repository.Deleted, error) {
+	var deleted repository.Deleted
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.Where("id = ?", id).First(&category).Error; err != nil {
+			return translate(err)
+		}
+
+		now := time.Now()
+		switch {
+		case opts.ReassignTo != 0:
+			parent, err := parentOf(tx, &opts.ReassignTo)
+			if err != nil {
+				return err
+			}
+
+			var children []models.Category
+			if err := tx.Where("parent_id = ?", id).Find(&children).Error; err != nil {
+				return err
+			}
+			for _, child := range children {
+				if err := move(tx, child, parent); err != nil {
+					return err
+				}
+			}
+
+			err = tx.Model(&models.Subcategory{}).
+				Where("category_id = ?", id).
+				Update("category_id", opts.ReassignTo).Error
+			if err != nil {
+				return translate(err)
+			}
+		case opts.Cascade:
+			subtree := category.Path + "%"
+			var err error
+			deleted.Ads, err = deleteAds(tx, now, "categories.path LIKE ?", subtree)
+			if err != nil {
+				return err
+			}
+
+			inSubtree := "category_id IN (SELECT id FROM categories WHERE path LIKE ? AND deleted_at IS NULL)"
+			err = tx.Preload("Category").Where(inSubtree, subtree).Order("id").Find(&deleted.Subcategories).Error
+			if err != nil {
+				return err
+			}
+			if err := withSubcategoryTranslations(tx, deleted.Subcategories); err != nil {
+				return err
+			}
+			err = tx.Model(&models.Subcategory{}).Where(inSubtree, subtree).Update("deleted_at", now).Error
+			if err != nil {
+				return err
+			}
+
+			err = tx.Where("path LIKE ? AND id <> ?", subtree, id).Order("path").Find(&deleted.Categories).Error
+			if err != nil {
+				return err
+			}
+			if err := withCategoryTranslations(tx, deleted.Categories); err != nil {
+				return err
+			}
+			err = tx.Model(&models.Category{}).
+				Where("path LIKE ? AND id <> ?", subtree, id).
+				Update("deleted_at", now).Error
+			if err != nil {
+				return err
+			}
+		default:
+			hasChildren, err := hasLive(tx, "categories", "parent_id = ?", id)
+			if err != nil {
+				return err
+			}
+			hasSubcategories, err := hasLive(tx, "subcategories", "category_id = ?", id)
+			if err != nil {
+				return err
+			}
+			if hasChildren || hasSubcategories {
+				return repository.ErrForeignKey
+			}
+		}
+
+		return tx.Model(&category).Update("deleted_at", now).Error
+	})
+	if err != nil {
+		return repository.Deleted{}, err
+	}
+	return deleted, nil
+}
+
+func (r *categoryRepository) Restore(ctx context.Context, id uint) error {
+	return translate(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&category).Error
+		if err != nil {
+			return translate(err)
+		}
+
+		if category.ParentID != nil {
+			if err := requireLive(tx, "categories", *category.ParentID); err != nil {
+				return err
+			}
+		}
+
+		// Everything below that was deleted at the same time went with the
+		// category.
+		subtree, deletedAt := category.Path+"%", category.DeletedAt.Time
+		err = tx.Exec(`UPDATE advertisements SET deleted_at = NULL
+			WHERE deleted_at = ?
+			AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+			AND subcategory_id IN (
+				SELECT subcategories.id FROM subcategories
+				JOIN categories ON categories.id = subcategories.category_id
+				WHERE categories.path LIKE ? AND subcategories.deleted_at = ?)`,
+			deletedAt, subtree, deletedAt).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec(`UPDATE subcategories SET deleted_at = NULL
+			WHERE deleted_at = ?
+			AND category_id IN (SELECT id FROM categories WHERE path LIKE ? AND deleted_at = ?)`,
+			deletedAt, subtree, deletedAt).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Exec(`UPDATE categories SET deleted_at = NULL WHERE path LIKE ? AND deleted_at = ?`,
+			subtree, deletedAt).Error
+	}))
+}
+
+// restoreAds brings back the ads of the subcategory that were deleted at
+// the given time, unless their owner is deleted.
+func restoreAds(tx *gorm.DB, subcategoryID uint, deletedAt time.Time) error {
+	return tx.Unscoped().
+		Model(&models.Advertisement{}).
+		Where("subcategory_id = ? AND deleted_at = ?", subcategoryID, deletedAt).
+		Where("user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)").
+		Update("deleted_at", nil).Error
+}
+
+type subcategoryRepository struct {
+	db *gorm.DB
+}
+
+func (r *subcategoryRepository) List(ctx context.Context) ([]models.Subcategory, error) {
+	var subcategories []models.Subcategory
+	db := r.db.WithContext(ctx)
+	if err := db.Preload("Category").Order("id").Find(&subcategories).Error; err != nil {
+		return nil, err
+	}
+	return subcategories, withSubcategoryTranslations(db, subcategories)
+}
+
+func (r *subcategoryRepository) Get(ctx context.Context, id uint) (models.Subcategory, error) {
+	return r.get(r.db.WithContext(ctx), "id = ?", id)
+}
+
+func (r *subcategoryRepository) GetByName(ctx context.Context, name string) (models.Subcategory, error) {
+	return r.get(r.db.WithContext(ctx), "name = ?", name)
+}
+
+func (r *subcategoryRepository) get(db *gorm.DB, query string, arg interface{}) (models.Subcategory, error) {
+	var subcategory models.Subcategory
+	if err := db.Preload("Category").Where(query, arg).First(&subcategory).Error; err != nil {
+		return subcategory, translate(err)
+	}
+
+	var err error
+	subcategory.Translations, err = translationsOf(db, models.EntitySubcategory, subcategory.ID)
+	return subcategory, err
+}
+
+func (r *subcategoryRepository) Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error) {
+	return lookupTranslations(r.db.WithContext(ctx), models.EntitySubcategory, "subcategories", locales)
+}
+
+func (r *subcategoryRepository) Create(ctx context.Context, subcategory *models.Subcategory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Category").Create(subcategory).Error; err != nil {
+			return translate(err)
+		}
+		subcategory.Translations = orEmpty(subcategory.Translations)
+		return saveTranslations(tx, models.EntitySubcategory, subcategory.ID, subcategory.Translations)
+	})
+}
+
+func (r *subcategoryRepository) Update(ctx context.Context, subcategory *models.Subcategory) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Category").Save(subcategory).Error; err != nil {
+			return translate(err)
+		}
+		subcategory.Translations = orEmpty(subcategory.Translations)
+		return saveTranslations(tx, models.EntitySubcategory, subcategory.ID, subcategory.Translations)
+	})
+}
+
+func (r *subcategoryRepository) Dependents(ctx context.Context, id uint) (repository.Dependents, error) {
+	var dependents repository.Dependents
+	err := r.db.WithContext(ctx).
+		Model(&models.Advertisement{}).
+		Where("subcategory_id = ?", id).
+		Count(&dependents.Ads).Error
+	return dependents, err
+}
+
+func (r *subcategoryRepository) Delete(ctx context.Context, id uint, opts repository.DeleteOptions) (repository.Deleted, error) {
+	var deleted repository.Deleted
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		switch {
+		case opts.ReassignTo != 0:
+			err := tx.Model(&models.Advertisement{}).
+				Where("subcategory_id = ?", id).
+				Update("subcategory_id", opts.ReassignTo).Error
+			if err != nil {
+				return translate(err)
+			}
+		case opts.Cascade:
+			var err error
+			deleted.Ads, err = deleteAds(tx, now, "advertisements.subcategory_id = ?", id)
+			if err != nil {
+				return err
+			}
+		default:
+			used, err := hasLive(tx, "advertisements", "subcategory_id = ?", id)
+			if err != nil {
+				return err
+			}
+			if used {
+				return repository.ErrForeignKey
+			}
+		}
+
+		return affected(tx.Model(&models.Subcategory{}).Where("id = ?", id).Update("deleted_at", now))
+	})
+	if err != nil {
+		return repository.Deleted{}, err
+	}
+	return deleted, nil
+}
+
+func (r *subcategoryRepository) Restore(ctx context.Context, id uint) error {
+	return translate(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var subcategory models.Subcategory
+		err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&subcategory).Error
+		if err != nil {
+			return translate(err)
+		}
+
+		if err := requireLive(tx, "categories", subcategory.CategoryID); err != nil {
+			return err
+		}
+		if err := restoreAds(tx, id, subcategory.DeletedAt.Time); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&subcategory).Update("deleted_at", nil).Error
+	}))
+}
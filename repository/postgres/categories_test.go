@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+func TestCategoryNamesAreUniquePerParent(t *testing.T) {
+	repos, _ := testRepos(t)
+	ctx := context.Background()
+
+	create := func(name string, parentID *uint) (uint, error) {
+		category := models.Category{Name: name, ParentID: parentID}
+		err := repos.Categories.Create(ctx, &category)
+		return category.ID, err
+	}
+	garden, err := create("Garden", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	house, err := create("House", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools, err := create("Tools", &garden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := create("Tools", &house); err != nil {
+		t.Errorf("the same name under another parent: %v", err)
+	}
+	if _, err := create("Tools", &garden); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("a duplicate child returned %v, want ErrDuplicate", err)
+	}
+	if _, err := create("Garden", nil); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("a duplicate top-level category returned %v, want ErrDuplicate", err)
+	}
+
+	moved := models.Category{ID: tools, Name: "Tools", ParentID: &house}
+	if err := repos.Categories.Update(ctx, &moved); !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("moving next to a namesake returned %v, want ErrDuplicate", err)
+	}
+	_, err = repos.Categories.Delete(ctx, garden, repository.DeleteOptions{ReassignTo: house})
+	if !errors.Is(err, repository.ErrDuplicate) {
+		t.Errorf("reassigning to a parent with a namesake returned %v, want ErrDuplicate", err)
+	}
+}
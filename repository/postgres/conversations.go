@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type conversationRepository struct {
+	db *gorm.DB
+}
+
+func (r *conversationRepository) Start(ctx context.Context, adID, buyerID, sellerID uint) (models.Conversation, bool, error) {
+	conversation := models.Conversation{AdID: adID, BuyerID: buyerID, SellerID: sellerID}
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "ad_id"}, {Name: "buyer_id"}},
+			DoNothing: true,
+		}).
+		Create(&conversation)
+	if result.Error != nil {
+		return models.Conversation{}, false, translate(result.Error)
+	}
+
+	err := r.db.WithContext(ctx).
+		Where("ad_id = ? AND buyer_id = ?", adID, buyerID).
+		First(&conversation).Error
+	return conversation, result.RowsAffected > 0, translate(err)
+}
+
+func (r *conversationRepository) Get(ctx context.Context, id uint) (models.Conversation, error) {
+	var conversation models.Conversation
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&conversation).Error
+	return conversation, translate(err)
+}
+
+func (r *conversationRepository) ListForUser(ctx context.Context, userID uint) ([]models.ConversationSummary, error) {
+	var conversations []models.ConversationSummary
+	err := r.db.WithContext(ctx).
+		Table("conversations").
+		Select(`conversations.*,
+			advertisements.title AS ad_title,
+			(SELECT count(*) FROM messages
+			 WHERE messages.conversation_id = conversations.id
+			   AND messages.sender_id <> ?
+			   AND messages.read_at IS NULL) AS unread`, userID).
+		Joins("JOIN advertisements ON advertisements.id = conversations.ad_id").
+		Where("conversations.buyer_id = ? OR conversations.seller_id = ?", userID, userID).
+		Order("COALESCE(conversations.last_message_at, conversations.created_at) DESC, conversations.id DESC").
+		Scan(&conversations).Error
+	return conversations, err
+}
+
+func (r *conversationRepository) Messages(ctx context.Context, conversationID, afterID uint, limit int) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.WithContext(ctx).
+		Where("conversation_id = ? AND id > ?", conversationID, afterID).
+		Order("id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *conversationRepository) AddMessage(ctx context.Context, message *models.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return translate(err)
+		}
+		return affected(tx.Model(&models.Conversation{}).
+			Where("id = ?", message.ConversationID).
+			Update("last_message_at", message.CreatedAt))
+	})
+}
+
+func (r *conversationRepository) MarkRead(ctx context.Context, conversationID, readerID uint) ([]models.Message, error) {
+	var messages []models.Message
+	err := r.db.WithContext(ctx).
+		Raw(`UPDATE messages SET read_at = ?
+			WHERE conversation_id = ? AND sender_id <> ? AND read_at IS NULL
+			RETURNING *`, time.Now(), conversationID, readerID).
+		Scan(&messages).Error
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, err
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type favoriteRepository struct {
+	db *gorm.DB
+}
+
+func (r *favoriteRepository) Add(ctx context.Context, userID, adID uint) (bool, error) {
+	var added bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireLive(tx, "advertisements", adID); err != nil {
+			return err
+		}
+
+		favorite := models.Favorite{UserID: userID, AdID: adID}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&favorite)
+		added = result.RowsAffected > 0
+		return translate(result.Error)
+	})
+	return added, err
+}
+
+func (r *favoriteRepository) Remove(ctx context.Context, userID, adID uint) error {
+	return affected(r.db.WithContext(ctx).
+		Where("user_id = ? AND ad_id = ?", userID, adID).
+		Delete(&models.Favorite{}))
+}
+
+func (r *favoriteRepository) List(ctx context.Context, userID uint, limit, offset int) (repository.AdPage, error) {
+	ads := &adRepository{db: r.db}
+	query := ads.query(ctx).
+		Joins("JOIN favorites ON favorites.ad_id = advertisements.id").
+		Where("favorites.user_id = ?", userID).
+		Where("advertisements.status NOT IN ? OR advertisements.user_id = ?", models.PrivateStatuses, userID).
+		Session(&gorm.Session{})
+
+	fetch := query.
+		Select(adColumns).
+		Order("favorites.created_at DESC, advertisements.id")
+
+	return page(query, fetch, limit, offset)
+}
+
+func (r *favoriteRepository) Contains(ctx context.Context, userID uint, adIDs []uint) (map[uint]bool, error) {
+	contains := make(map[uint]bool)
+	if len(adIDs) == 0 {
+		return contains, nil
+	}
+
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&models.Favorite{}).
+		Where("user_id = ? AND ad_id IN ?", userID, adIDs).
+		Pluck("ad_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		contains[id] = true
+	}
+	return contains, nil
+}
+
+func (r *favoriteRepository) Users(ctx context.Context, adID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&models.Favorite{}).
+		Where("ad_id = ?", adID).
+		Pluck("user_id", &ids).Error
+	return ids, err
+}
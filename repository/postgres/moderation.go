@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+// reportedSQL joins the number of open reports of each ad as
+// reported.open_reports, which is NULL for ads without any.
+const reportedSQL = `LEFT JOIN (
+	SELECT ad_id, count(*) AS open_reports FROM reports
+	WHERE resolved_at IS NULL
+	GROUP BY ad_id
+) reported ON reported.ad_id = advertisements.id`
+
+func (r *moderationRepository) Report(ctx context.Context, report *models.Report) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := requireLive(tx, "advertisements", report.AdID); err != nil {
+			return err
+		}
+		return translate(tx.Create(report).Error)
+	})
+}
+
+func (r *moderationRepository) Queue(ctx context.Context, limit, offset int) (repository.AdPage, error) {
+	ads := &adRepository{db: r.db}
+	query := ads.query(ctx).
+		Joins(reportedSQL).
+		Where("advertisements.status <> ?", models.StatusDraft).
+		Where("reported.open_reports IS NOT NULL OR advertisements.reviewed_at IS NULL").
+		Session(&gorm.Session{})
+
+	fetch := query.
+		Select(adColumns).
+		Order("reported.open_reports DESC NULLS LAST, advertisements.id")
+
+	return page(query, fetch, limit, offset)
+}
+
+func (r *moderationRepository) OpenReports(ctx context.Context, adIDs []uint) (map[uint][]models.Report, error) {
+	reports := make(map[uint][]models.Report)
+	if len(adIDs) == 0 {
+		return reports, nil
+	}
+
+	var rows []models.Report
+	err := r.db.WithContext(ctx).
+		Where("ad_id IN ? AND resolved_at IS NULL", adIDs).
+		Order("created_at, id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, report := range rows {
+		reports[report.AdID] = append(reports[report.AdID], report)
+	}
+	return reports, nil
+}
+
+func (r *moderationRepository) Act(ctx context.Context, action *models.ModerationAction) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":      action.ToStatus,
+			"reviewed_at": now,
+		}
+		if action.ToStatus == models.StatusActive {
+			updates["matched_at"] = nil
+		}
+		err := affected(tx.Model(&models.Advertisement{}).
+			Where("id = ? AND status = ?", action.AdID, action.FromStatus).
+			Updates(updates))
+		if err != nil {
+			return err
+		}
+
+		action.CreatedAt = now
+		if err := tx.Create(action).Error; err != nil {
+			return translate(err)
+		}
+
+		return tx.Model(&models.Report{}).
+			Where("ad_id = ? AND resolved_at IS NULL", action.AdID).
+			Updates(map[string]interface{}{
+				"resolved_at": now,
+				"action_id":   action.ID,
+			}).Error
+	})
+}
+
+func (r *moderationRepository) Actions(ctx context.Context, adID uint) ([]models.ModerationAction, error) {
+	var actions []models.ModerationAction
+	err := r.db.WithContext(ctx).
+		Where("ad_id = ?", adID).
+		Order("created_at DESC, id DESC").
+		Find(&actions).Error
+	return actions, err
+}
@@ -0,0 +1,84 @@
+// Package postgres implements the repositories with GORM on PostgreSQL
+// with PostGIS. The schema is managed by package migrations.
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/sciphilib/go-dacha/common"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+func New(db *gorm.DB) repository.Repositories {
+	return repository.Repositories{
+		Ads:           &adRepository{db: db},
+		Users:         &userRepository{db: db},
+		Categories:    &categoryRepository{db: db},
+		Subcategories: &subcategoryRepository{db: db},
+		RefreshTokens: &refreshTokenRepository{db: db},
+		Favorites:     &favoriteRepository{db: db},
+		Conversations: &conversationRepository{db: db},
+		SavedSearches: &savedSearchRepository{db: db},
+		Notifications: &notificationRepository{db: db},
+		Moderation:    &moderationRepository{db: db},
+		Audit:         &auditRepository{db: db},
+	}
+}
+
+// translate maps GORM errors to the repository ones. The connection must
+// be opened with TranslateError for constraint violations to be
+// recognized.
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return repository.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return repository.ErrDuplicate
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return repository.ErrForeignKey
+	}
+	return err
+}
+
+// affected turns a write that matched no rows into ErrNotFound.
+func affected(result *gorm.DB) error {
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func geoJSON(text sql.NullString) common.GeoJSONText {
+	if !text.Valid {
+		return common.GeoJSONText{Data: json.RawMessage("{}")}
+	}
+	return common.GeoJSONText{Data: json.RawMessage(text.String)}
+}
+
+// requireLive returns ErrForeignKey unless the row with the given ID in
+// table exists and is not soft-deleted.
+func requireLive(tx *gorm.DB, table string, id uint) error {
+	var count int64
+	err := tx.Table(table).Where("id = ? AND deleted_at IS NULL", id).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return repository.ErrForeignKey
+	}
+	return nil
+}
+
+// hasLive tells whether any row of table that is not soft-deleted matches
+// the condition.
+func hasLive(tx *gorm.DB, table string, query string, args ...interface{}) (bool, error) {
+	var count int64
+	err := tx.Table(table).Where(query, args...).Where("deleted_at IS NULL").Count(&count).Error
+	return count > 0, err
+}
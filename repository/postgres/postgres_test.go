@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/migrations"
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	pg "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testRepos migrates the database named by TEST_DATABASE_DSN, empties it
+// and returns the repositories on it. The test is skipped when the
+// variable is unset. The database must have PostGIS available.
+func testRepos(t *testing.T) (repository.Repositories, *gorm.DB) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(pg.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []string
+	err = db.Raw(`SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema()
+		AND tablename NOT IN ('schema_migrations', 'spatial_ref_sys')`).
+		Scan(&tables).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return New(db), db
+}
+
+// testUser creates a user whose email and phone number derive from name.
+func testUser(t *testing.T, repos repository.Repositories, name string) models.User {
+	t.Helper()
+
+	user := models.User{Name: name, Email: name + "@example.com", PhoneNumber: "+" + name, Role: models.RoleUser}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// testSubcategory creates the category and its subcategory.
+func testSubcategory(t *testing.T, repos repository.Repositories, category, name string) models.Subcategory {
+	t.Helper()
+
+	ctx := context.Background()
+	parent := models.Category{Name: category}
+	if err := repos.Categories.Create(ctx, &parent); err != nil {
+		t.Fatal(err)
+	}
+	subcategory := models.Subcategory{Name: name, CategoryID: parent.ID}
+	if err := repos.Subcategories.Create(ctx, &subcategory); err != nil {
+		t.Fatal(err)
+	}
+	return subcategory
+}
@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/sciphilib/go-dacha/models"
+	"gorm.io/gorm"
+)
+
+type savedSearchRepository struct {
+	db *gorm.DB
+}
+
+func (r *savedSearchRepository) List(ctx context.Context, userID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id").Find(&searches).Error
+	return searches, err
+}
+
+func (r *savedSearchRepository) Get(ctx context.Context, id uint) (models.SavedSearch, error) {
+	var search models.SavedSearch
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&search).Error
+	return search, translate(err)
+}
+
+func (r *savedSearchRepository) Create(ctx context.Context, search *models.SavedSearch) error {
+	return translate(r.db.WithContext(ctx).Create(search).Error)
+}
+
+func (r *savedSearchRepository) Update(ctx context.Context, search *models.SavedSearch) error {
+	return translate(r.db.WithContext(ctx).Save(search).Error)
+}
+
+func (r *savedSearchRepository) Delete(ctx context.Context, id uint) error {
+	return affected(r.db.WithContext(ctx).Delete(&models.SavedSearch{}, id))
+}
+
+// matchingSQL selects the saved searches of live users that match the ad
+// bound to it, with the same semantics as applyFilter and applyGeoFilter.
+const matchingSQL = `
+	SELECT saved_searches.*
+	FROM saved_searches
+	JOIN advertisements ON advertisements.id = ?
+	JOIN subcategories ON subcategories.id = advertisements.subcategory_id
+	JOIN categories ON categories.id = subcategories.category_id
+	JOIN users ON users.id = saved_searches.user_id
+	WHERE advertisements.status = 'active'
+	AND advertisements.deleted_at IS NULL
+	AND users.deleted_at IS NULL
+	AND saved_searches.user_id <> advertisements.user_id
+	AND (saved_searches.category_id IS NULL OR categories.path LIKE (
+		SELECT path || '%' FROM categories AS searched WHERE searched.id = saved_searches.category_id))
+	AND (saved_searches.subcategory_id IS NULL OR saved_searches.subcategory_id = advertisements.subcategory_id)
+	AND (saved_searches.price_min IS NULL
+		OR (advertisements.price_amount >= saved_searches.price_min AND NOT advertisements.price_free))
+	AND (saved_searches.price_max IS NULL
+		OR advertisements.price_amount <= saved_searches.price_max OR advertisements.price_free)
+	AND (saved_searches.currency = ''
+		OR advertisements.price_currency = saved_searches.currency OR advertisements.price_free)
+	AND (saved_searches.query = '' OR advertisements.search_vector @@ (
+		websearch_to_tsquery('russian', saved_searches.query) ||
+		websearch_to_tsquery('english', saved_searches.query)))
+	AND (saved_searches.radius IS NULL OR ST_DWithin(
+		advertisements.location,
+		ST_SetSRID(ST_MakePoint(saved_searches.lon, saved_searches.lat), 4326)::geography,
+		saved_searches.radius))
+	ORDER BY saved_searches.id`
+
+func (r *savedSearchRepository) Matching(ctx context.Context, adID uint) ([]models.SavedSearch, error) {
+	var searches []models.SavedSearch
+	err := r.db.WithContext(ctx).Raw(matchingSQL, adID).Scan(&searches).Error
+	return searches, err
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *notificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return translate(r.db.WithContext(ctx).Create(notification).Error)
+}
+
+func (r *notificationRepository) List(ctx context.Context, userID uint, limit, offset int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, userID, id uint) (models.Notification, bool, error) {
+	var notification models.Notification
+	err := r.db.WithContext(ctx).
+		Raw(`UPDATE notifications SET read_at = now()
+			WHERE id = ? AND user_id = ? AND read_at IS NULL
+			RETURNING *`, id, userID).
+		Scan(&notification).Error
+	if err != nil || notification.ID != 0 {
+		return notification, notification.ID != 0, err
+	}
+
+	err = r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+	return notification, false, translate(err)
+}
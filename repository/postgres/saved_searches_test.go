@@ -0,0 +1,188 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+func TestSavedSearchCRUD(t *testing.T) {
+	repos, _ := testRepos(t)
+	ctx := context.Background()
+	buyer := testUser(t, repos, "buyer")
+	other := testUser(t, repos, "other")
+	tools := testSubcategory(t, repos, "Garden", "Tools")
+
+	priceMax := int64(1000)
+	search := models.SavedSearch{UserID: buyer.ID, Name: "Cheap tools", SubcategoryID: &tools.ID, PriceMax: &priceMax}
+	if err := repos.SavedSearches.Create(ctx, &search); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.SavedSearches.Create(ctx, &models.SavedSearch{UserID: other.ID, Name: "Anything"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repos.SavedSearches.Get(ctx, search.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != search.Name || got.SubcategoryID == nil || *got.SubcategoryID != tools.ID || got.PriceMax == nil || *got.PriceMax != priceMax {
+		t.Errorf("got %+v, want %+v", got, search)
+	}
+
+	search.Name = "Tools"
+	search.PriceMax = nil
+	if err := repos.SavedSearches.Update(ctx, &search); err != nil {
+		t.Fatal(err)
+	}
+	list, err := repos.SavedSearches.List(ctx, buyer.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "Tools" || list[0].PriceMax != nil {
+		t.Errorf("buyer's saved searches %+v", list)
+	}
+
+	if err := repos.SavedSearches.Delete(ctx, search.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.SavedSearches.Get(ctx, search.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrNotFound", err)
+	}
+	if err := repos.SavedSearches.Delete(ctx, search.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("second Delete returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestSavedSearchMatching(t *testing.T) {
+	repos, db := testRepos(t)
+	ctx := context.Background()
+	seller := testUser(t, repos, "seller")
+	buyer := testUser(t, repos, "buyer")
+	tools := testSubcategory(t, repos, "Garden", "Tools")
+
+	// Moscow; Khimki is about 19 km and Tver about 160 km away.
+	lat, lon := 55.7558, 37.6173
+	ad := func(price models.Price, lat, lon float64) uint {
+		t.Helper()
+		ad := models.Advertisement{
+			Title:          "Rusty spade",
+			Price:          price,
+			Subcategory_id: tools.ID,
+			User_id:        seller.ID,
+			Datetime:       time.Now(),
+			Status:         models.StatusActive,
+			ExpiresAt:      time.Now().Add(time.Hour),
+		}
+		if err := repos.Ads.Create(ctx, &ad); err != nil {
+			t.Fatal(err)
+		}
+		err := db.Exec("UPDATE advertisements SET location = "+originSQL+" WHERE id = ?", lon, lat, ad.ID).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ad.ID
+	}
+	cheap := ad(models.Price{Amount: 500, Currency: "RUB"}, 55.8890, 37.4450)
+	dear := ad(models.Price{Amount: 5000, Currency: "RUB"}, lat, lon)
+	dollars := ad(models.Price{Amount: 500, Currency: "USD"}, lat, lon)
+	free := ad(models.Price{Free: true}, 56.8587, 35.9176)
+
+	int64p := func(v int64) *int64 { return &v }
+	float64p := func(v float64) *float64 { return &v }
+	searches := []models.SavedSearch{
+		{Name: "any"},
+		{Name: "min 1000", PriceMin: int64p(1000)},
+		{Name: "max 1000", PriceMax: int64p(1000)},
+		{Name: "500 to 1000 RUB", PriceMin: int64p(500), PriceMax: int64p(1000), Currency: "RUB"},
+		{Name: "within 25 km", Lat: &lat, Lon: &lon, Radius: float64p(25000)},
+		{Name: "within 10 km", Lat: &lat, Lon: &lon, Radius: float64p(10000)},
+	}
+	for i := range searches {
+		searches[i].UserID = buyer.ID
+		if err := repos.SavedSearches.Create(ctx, &searches[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	own := models.SavedSearch{UserID: seller.ID, Name: "own"}
+	if err := repos.SavedSearches.Create(ctx, &own); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ad   uint
+		want []string
+	}{
+		{"cheap in Khimki", cheap, []string{"any", "max 1000", "500 to 1000 RUB", "within 25 km"}},
+		{"dear in Moscow", dear, []string{"any", "min 1000", "within 25 km", "within 10 km"}},
+		{"dollars in Moscow", dollars, []string{"any", "max 1000", "within 25 km", "within 10 km"}},
+		{"free in Tver", free, []string{"any", "max 1000"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matching, err := repos.SavedSearches.Matching(ctx, test.ad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, search := range matching {
+				got = append(got, search.Name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("matching searches %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnmatchedAds(t *testing.T) {
+	repos, _ := testRepos(t)
+	ctx := context.Background()
+	seller := testUser(t, repos, "seller")
+	tools := testSubcategory(t, repos, "Garden", "Tools")
+
+	ad := func(status string) uint {
+		t.Helper()
+		ad := models.Advertisement{
+			Title:          status,
+			Subcategory_id: tools.ID,
+			User_id:        seller.ID,
+			Datetime:       time.Now(),
+			Status:         status,
+			ExpiresAt:      time.Now().Add(time.Hour),
+		}
+		if err := repos.Ads.Create(ctx, &ad); err != nil {
+			t.Fatal(err)
+		}
+		return ad.ID
+	}
+	unmatched := func() []uint {
+		t.Helper()
+		ids, err := repos.Ads.Unmatched(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+
+	active := ad(models.StatusActive)
+	draft := ad(models.StatusDraft)
+	if got, want := unmatched(), []uint{active}; !slices.Equal(got, want) {
+		t.Fatalf("unmatched ads %v, want %v", got, want)
+	}
+	if err := repos.Ads.MarkMatched(ctx, active); err != nil {
+		t.Fatal(err)
+	}
+	if err := repos.Ads.SetStatus(ctx, draft, models.StatusDraft, models.StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := unmatched(), []uint{draft}; !slices.Equal(got, want) {
+		t.Errorf("unmatched ads %v, want %v", got, want)
+	}
+}
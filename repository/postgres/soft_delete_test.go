@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+)
+
+// TestRestoreTakenKey checks that the unique indexes cover live rows only:
+// a deleted row's key can be reused, and the row cannot be restored while
+// a live one holds it.
+func TestRestoreTakenKey(t *testing.T) {
+	repos, _ := testRepos(t)
+	ctx := context.Background()
+	garden := models.Category{Name: "Garden"}
+	if err := repos.Categories.Create(ctx, &garden); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		create  func() (uint, error)
+		delete  func(id uint) error
+		restore func(id uint) error
+	}{
+		{"user", func() (uint, error) {
+			user := models.User{Name: "seller", Email: "seller@example.com", PhoneNumber: "+1", Role: models.RoleUser}
+			err := repos.Users.Create(ctx, &user)
+			return user.ID, err
+		}, func(id uint) error {
+			_, err := repos.Users.Delete(ctx, id)
+			return err
+		}, func(id uint) error {
+			return repos.Users.Restore(ctx, id)
+		}},
+		{"category", func() (uint, error) {
+			category := models.Category{Name: "Plants"}
+			err := repos.Categories.Create(ctx, &category)
+			return category.ID, err
+		}, func(id uint) error {
+			_, err := repos.Categories.Delete(ctx, id, repository.DeleteOptions{})
+			return err
+		}, func(id uint) error {
+			return repos.Categories.Restore(ctx, id)
+		}},
+		{"subcategory", func() (uint, error) {
+			subcategory := models.Subcategory{Name: "Tools", CategoryID: garden.ID}
+			err := repos.Subcategories.Create(ctx, &subcategory)
+			return subcategory.ID, err
+		}, func(id uint) error {
+			_, err := repos.Subcategories.Delete(ctx, id, repository.DeleteOptions{})
+			return err
+		}, func(id uint) error {
+			return repos.Subcategories.Restore(ctx, id)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deleted, err := test.create()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := test.create(); !errors.Is(err, repository.ErrDuplicate) {
+				t.Fatalf("creating a duplicate returned %v, want ErrDuplicate", err)
+			}
+			if err := test.delete(deleted); err != nil {
+				t.Fatal(err)
+			}
+
+			taken, err := test.create()
+			if err != nil {
+				t.Fatalf("reusing the key of a deleted %s: %v", test.name, err)
+			}
+			if err := test.restore(deleted); !errors.Is(err, repository.ErrDuplicate) {
+				t.Errorf("Restore returned %v, want ErrDuplicate", err)
+			}
+
+			if err := test.delete(taken); err != nil {
+				t.Fatal(err)
+			}
+			if err := test.restore(deleted); err != nil {
+				t.Errorf("Restore after the key was freed: %v", err)
+			}
+		})
+	}
+}
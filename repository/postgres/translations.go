@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"github.com/sciphilib/go-dacha/models"
+	"gorm.io/gorm"
+)
+
+// loadTranslations returns the translations of the entities by ID.
+func loadTranslations(tx *gorm.DB, entity string, ids []uint) (map[uint]models.Translations, error) {
+	var rows []models.Translation
+	err := tx.Where("entity = ? AND entity_id IN ?", entity, ids).Find(&rows).Error
+	return byEntity(rows), err
+}
+
+// translationsOf returns the translations of one entity.
+func translationsOf(tx *gorm.DB, entity string, id uint) (models.Translations, error) {
+	translations, err := loadTranslations(tx, entity, []uint{id})
+	return orEmpty(translations[id]), err
+}
+
+// lookupTranslations returns the translations into the locales of the
+// live entities stored in table.
+func lookupTranslations(tx *gorm.DB, entity, table string, locales []string) (map[uint]models.Translations, error) {
+	var rows []models.Translation
+	err := tx.
+		Where("entity = ? AND locale IN ?", entity, locales).
+		Where("entity_id IN (SELECT id FROM " + table + " WHERE deleted_at IS NULL)").
+		Find(&rows).Error
+	return byEntity(rows), err
+}
+
+func byEntity(rows []models.Translation) map[uint]models.Translations {
+	translations := make(map[uint]models.Translations)
+	for _, row := range rows {
+		if translations[row.EntityID] == nil {
+			translations[row.EntityID] = make(models.Translations)
+		}
+		translations[row.EntityID][row.Locale] = row.Name
+	}
+	return translations
+}
+
+// saveTranslations replaces the translations of the entity.
+func saveTranslations(tx *gorm.DB, entity string, id uint, translations models.Translations) error {
+	err := tx.Where("entity = ? AND entity_id = ?", entity, id).Delete(&models.Translation{}).Error
+	if err != nil || len(translations) == 0 {
+		return err
+	}
+
+	rows := make([]models.Translation, 0, len(translations))
+	for locale, name := range translations {
+		rows = append(rows, models.Translation{Entity: entity, EntityID: id, Locale: locale, Name: name})
+	}
+	return tx.Create(&rows).Error
+}
+
+// withCategoryTranslations fills in the translations of the categories.
+func withCategoryTranslations(tx *gorm.DB, categories []models.Category) error {
+	if len(categories) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	translations, err := loadTranslations(tx, models.EntityCategory, ids)
+	if err != nil {
+		return err
+	}
+	for i := range categories {
+		categories[i].Translations = orEmpty(translations[categories[i].ID])
+	}
+	return nil
+}
+
+// withSubcategoryTranslations fills in the translations of the
+// subcategories.
+func withSubcategoryTranslations(tx *gorm.DB, subcategories []models.Subcategory) error {
+	if len(subcategories) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(subcategories))
+	for i, subcategory := range subcategories {
+		ids[i] = subcategory.ID
+	}
+	translations, err := loadTranslations(tx, models.EntitySubcategory, ids)
+	if err != nil {
+		return err
+	}
+	for i := range subcategories {
+		subcategories[i].Translations = orEmpty(translations[subcategories[i].ID])
+	}
+	return nil
+}
+
+func orEmpty(translations models.Translations) models.Translations {
+	if translations == nil {
+		return models.Translations{}
+	}
+	return translations
+}
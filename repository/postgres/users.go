@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+	"github.com/sciphilib/go-dacha/repository"
+	"gorm.io/gorm"
+)
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+type userRow struct {
+	models.User
+	LocationText sql.NullString
+}
+
+const userColumns = `users.*, ST_AsGeoJSON(users.location::geometry) AS location_text`
+
+func (row userRow) user() models.User {
+	user := row.User
+	user.LocationText = geoJSON(row.LocationText)
+	return user
+}
+
+func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
+	var rows []userRow
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select(userColumns).
+		Where("users.deleted_at IS NULL").
+		Order("users.id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]models.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.user()
+	}
+	return users, nil
+}
+
+func (r *userRepository) get(ctx context.Context, query string, args ...interface{}) (models.User, error) {
+	var row userRow
+	err := r.db.WithContext(ctx).
+		Table("users").
+		Select(userColumns).
+		Where(query, args...).
+		Where("users.deleted_at IS NULL").
+		Take(&row).Error
+	if err != nil {
+		return models.User{}, translate(err)
+	}
+	return row.user(), nil
+}
+
+func (r *userRepository) Get(ctx context.Context, id uint) (models.User, error) {
+	return r.get(ctx, "users.id = ?", id)
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.get(ctx, "users.email = ?", email)
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Save(user).Error)
+}
+
+func (r *userRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	return affected(r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("id = ?", id).
+		Update("role", role))
+}
+
+// Delete soft-deletes the user together with their live ads, stamping both
+// with the same time so that Restore brings back exactly those ads, and
+// revokes the user's refresh tokens.
+func (r *userRepository) Delete(ctx context.Context, id uint) (repository.Deleted, error) {
+	var deleted repository.Deleted
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := affected(tx.Model(&models.User{}).Where("id = ?", id).Update("deleted_at", now))
+		if err != nil {
+			return err
+		}
+
+		deleted.Ads, err = deleteAds(tx, now, "advertisements.user_id = ?", id)
+		if err != nil {
+			return err
+		}
+
+		return tx.Where("user_id = ?", id).Delete(&models.RefreshToken{}).Error
+	})
+	if err != nil {
+		return repository.Deleted{}, err
+	}
+	return deleted, nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	return translate(r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		err := tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&user).Error
+		if err != nil {
+			return translate(err)
+		}
+
+		err = tx.Unscoped().
+			Model(&models.Advertisement{}).
+			Where("user_id = ? AND deleted_at = ?", id, user.DeletedAt.Time).
+			Where("subcategory_id IN (SELECT id FROM subcategories WHERE deleted_at IS NULL)").
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&user).Update("deleted_at", nil).Error
+	}))
+}
@@ -0,0 +1,352 @@
+// Package repository defines the storage interfaces used by the HTTP
+// handlers. Package postgres implements them with GORM on PostgreSQL and
+// package memory keeps everything in process, for tests and local runs.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sciphilib/go-dacha/models"
+)
+
+var (
+	ErrNotFound   = errors.New("record not found")
+	ErrDuplicate  = errors.New("record already exists")
+	ErrForeignKey = errors.New("foreign key constraint violated")
+	ErrLimit      = errors.New("limit exceeded")
+)
+
+// Users, categories, subcategories and ads are soft-deleted: Delete hides
+// them from every read, and the Restore methods bring them back. Unique
+// keys such as emails and names only need to be unique among live records,
+// so a deleted record's key can be taken. Restore returns ErrNotFound
+// unless the record exists and is deleted, ErrForeignKey while a record it
+// belongs to is still deleted, and ErrDuplicate when a live record has
+// taken its key.
+
+// Categories and subcategories are read with Translations filled in, and
+// Create and Update replace the stored translations with theirs.
+
+// Repositories bundles the repositories the HTTP handlers depend on.
+type Repositories struct {
+	Ads           AdRepository
+	Users         UserRepository
+	Categories    CategoryRepository
+	Subcategories SubcategoryRepository
+	RefreshTokens RefreshTokenRepository
+	Favorites     FavoriteRepository
+	Conversations ConversationRepository
+	SavedSearches SavedSearchRepository
+	Notifications NotificationRepository
+	Moderation    ModerationRepository
+	Audit         AuditRepository
+}
+
+type UserRepository interface {
+	List(ctx context.Context) ([]models.User, error)
+	Get(ctx context.Context, id uint) (models.User, error)
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	UpdateRole(ctx context.Context, id uint, role string) error
+	// Delete deletes the user with their ads and revokes their refresh
+	// tokens. Restore brings the ads back with the user, except those
+	// whose subcategory has been deleted since.
+	Delete(ctx context.Context, id uint) (Deleted, error)
+	Restore(ctx context.Context, id uint) error
+}
+
+// CategoryRepository keeps the category tree. Category names are unique
+// among the children of each parent and among the top-level categories.
+// Create and Update fill in Path and return ErrForeignKey when ParentID
+// names a missing category; Update moves the category with its descendants
+// when ParentID changes, and the caller must not move a category under one
+// of its own descendants.
+type CategoryRepository interface {
+	// List returns all categories, parents before their children.
+	List(ctx context.Context) ([]models.Category, error)
+	Get(ctx context.Context, id uint) (models.Category, error)
+	// Descendants returns the categories below the given one, parents
+	// before their children.
+	Descendants(ctx context.Context, id uint) ([]models.Category, error)
+	Create(ctx context.Context, category *models.Category) error
+	Update(ctx context.Context, category *models.Category) error
+	// Dependents counts the child categories and subcategories of the
+	// category and the ads anywhere below it.
+	Dependents(ctx context.Context, id uint) (Dependents, error)
+	// Delete deletes the category as told by opts, reassigning its child
+	// categories and subcategories to another category or deleting
+	// everything below it. The caller must not reassign to a descendant.
+	Delete(ctx context.Context, id uint, opts DeleteOptions) (Deleted, error)
+	// Restore also brings back everything that was deleted with the
+	// category. It returns ErrForeignKey while the parent is deleted.
+	Restore(ctx context.Context, id uint) error
+	// Translations returns the names of the categories in the given
+	// locales by category ID, leaving out categories without any.
+	Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error)
+}
+
+// SubcategoryRepository returns subcategories with Category filled in.
+type SubcategoryRepository interface {
+	List(ctx context.Context) ([]models.Subcategory, error)
+	Get(ctx context.Context, id uint) (models.Subcategory, error)
+	GetByName(ctx context.Context, name string) (models.Subcategory, error)
+	Create(ctx context.Context, subcategory *models.Subcategory) error
+	Update(ctx context.Context, subcategory *models.Subcategory) error
+	// Dependents counts the ads of the subcategory.
+	Dependents(ctx context.Context, id uint) (Dependents, error)
+	// Delete deletes the subcategory as told by opts, reassigning its ads
+	// to another subcategory or deleting them.
+	Delete(ctx context.Context, id uint, opts DeleteOptions) (Deleted, error)
+	// Restore also brings back the ads that were deleted with the
+	// subcategory.
+	Restore(ctx context.Context, id uint) error
+	// Translations returns the names of the subcategories in the given
+	// locales by subcategory ID, leaving out subcategories without any.
+	Translations(ctx context.Context, locales []string) (map[uint]models.Translations, error)
+}
+
+// ModerationRepository keeps the reports users file against ads and the
+// actions moderators take on them.
+type ModerationRepository interface {
+	// Report files a report. It returns ErrDuplicate while the user has
+	// an open report on the ad and ErrForeignKey when the ad is deleted.
+	Report(ctx context.Context, report *models.Report) error
+	// Queue returns a page of the ads awaiting review: ads with open
+	// reports, most reported first, then ads that were never reviewed,
+	// oldest first. Drafts are left out.
+	Queue(ctx context.Context, limit, offset int) (AdPage, error)
+	// OpenReports returns the open reports of the ads by ad ID, oldest
+	// first.
+	OpenReports(ctx context.Context, adIDs []uint) (map[uint][]models.Report, error)
+	// Act records the action and applies it in one transaction: the ad
+	// moves from FromStatus to ToStatus, is marked reviewed and has its
+	// open reports resolved by the action. An ad going active is matched
+	// against the saved searches again. It returns ErrNotFound unless
+	// the ad exists and still has FromStatus.
+	Act(ctx context.Context, action *models.ModerationAction) error
+	// Actions returns the actions taken on the ad, newest first.
+	Actions(ctx context.Context, adID uint) ([]models.ModerationAction, error)
+}
+
+// AuditRepository keeps the audit log. Entries are only ever added.
+type AuditRepository interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+	// List returns a page of the entries matching the filter, newest
+	// first.
+	List(ctx context.Context, filter AuditFilter) (AuditPage, error)
+}
+
+// Dependents counts the live records that belong to a category or a
+// subcategory.
+type Dependents struct {
+	Categories    int64
+	Subcategories int64
+	Ads           int64
+}
+
+// DeleteOptions says what happens to the records that belong to a
+// category or subcategory being deleted. With neither option set, Delete
+// returns ErrForeignKey while there are any. ReassignTo moves them to the
+// node with that ID, which must differ from the deleted one; the move
+// returns ErrDuplicate when it would break a unique name. Cascade deletes
+// them too.
+type DeleteOptions struct {
+	ReassignTo uint
+	Cascade    bool
+}
+
+// Deleted holds the records a delete took down together with the one it
+// was asked to delete, as they were before it.
+type Deleted struct {
+	Categories    []models.Category
+	Subcategories []models.Subcategory
+	Ads           []models.AdDetails
+}
+
+type AdRepository interface {
+	// List returns a page of ads matching the filter.
+	List(ctx context.Context, filter AdFilter) (AdPage, error)
+	// Search is List restricted to an area and ordered by distance.
+	Search(ctx context.Context, filter AdFilter, geo GeoFilter) (AdPage, error)
+	// Newest returns all active ads from newest to oldest.
+	Newest(ctx context.Context) ([]models.AdDetails, error)
+	// NearestTo returns all active ads from near to far from the user's
+	// location.
+	NearestTo(ctx context.Context, userID uint) ([]models.AdDetails, error)
+	GetDetails(ctx context.Context, id uint) (models.AdDetails, error)
+	Get(ctx context.Context, id uint) (models.Advertisement, error)
+	Create(ctx context.Context, ad *models.Advertisement) error
+	// Update saves every field except Pictures, Status, ExpiresAt,
+	// ReviewedAt and MatchedAt, which only change through their own
+	// methods so concurrent changes are not lost.
+	Update(ctx context.Context, ad *models.Advertisement) error
+	// SetStatus moves the ad from one status to another. It returns
+	// ErrNotFound unless the ad exists and has the from status. An ad
+	// leaving the rejected status is queued for review again, and an ad
+	// going active is matched against the saved searches again.
+	SetStatus(ctx context.Context, id uint, from, to string) error
+	// Renew moves the expiry of a draft, active or expired ad to until and
+	// makes an expired ad active again. It returns ErrNotFound unless the
+	// ad exists with one of those statuses.
+	Renew(ctx context.Context, id uint, until time.Time) error
+	// Expire marks the active ads that expired by now as expired and
+	// returns their IDs.
+	Expire(ctx context.Context, now time.Time) ([]uint, error)
+	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	// Unmatched returns up to limit active ads, oldest first, that have
+	// not been matched against the saved searches since they were created
+	// or last went active. They stay unmatched until MarkMatched, so a
+	// match that fails or is cut short by a restart is retried.
+	Unmatched(ctx context.Context, limit int) ([]uint, error)
+	MarkMatched(ctx context.Context, id uint) error
+	// AppendPictures, RemovePicture and SetPictures change the picture URLs
+	// of an ad and return the resulting list. AppendPictures returns
+	// ErrLimit, and appends nothing, when the ad would end up with more
+	// than limit pictures.
+	AppendPictures(ctx context.Context, id uint, pictures []string, limit int) ([]string, error)
+	RemovePicture(ctx context.Context, id uint, picture string) ([]string, error)
+	SetPictures(ctx context.Context, id uint, pictures []string) ([]string, error)
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (models.RefreshToken, error)
+	// Rotate revokes the token with the given ID and stores next in its
+	// place. It returns ErrNotFound if the token is already revoked.
+	Rotate(ctx context.Context, id uint, next *models.RefreshToken) error
+	// Revoke revokes the token with the given hash if it is still active.
+	Revoke(ctx context.Context, hash string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+}
+
+type FavoriteRepository interface {
+	// Add bookmarks an ad for a user and reports whether it was not
+	// bookmarked yet; adding it twice is not an error. It returns
+	// ErrForeignKey if the ad does not exist.
+	Add(ctx context.Context, userID, adID uint) (bool, error)
+	Remove(ctx context.Context, userID, adID uint) error
+	// List returns a page of the user's favorite ads, most recently added
+	// first. Ads of other users with a private status are left out.
+	List(ctx context.Context, userID uint, limit, offset int) (AdPage, error)
+	// Contains reports which of the given ads the user has bookmarked.
+	Contains(ctx context.Context, userID uint, adIDs []uint) (map[uint]bool, error)
+	// Users returns the IDs of the users who bookmarked the ad.
+	Users(ctx context.Context, adID uint) ([]uint, error)
+}
+
+type ConversationRepository interface {
+	// Start returns the buyer's conversation about the ad, creating it if
+	// there is none yet, and reports whether it did. It returns
+	// ErrForeignKey if the ad does not exist.
+	Start(ctx context.Context, adID, buyerID, sellerID uint) (models.Conversation, bool, error)
+	Get(ctx context.Context, id uint) (models.Conversation, error)
+	// ListForUser returns the conversations the user takes part in, most
+	// recently active first.
+	ListForUser(ctx context.Context, userID uint) ([]models.ConversationSummary, error)
+	// Messages returns up to limit messages with IDs greater than afterID,
+	// oldest first.
+	Messages(ctx context.Context, conversationID, afterID uint, limit int) ([]models.Message, error)
+	// AddMessage stores a message and records it as the latest activity of
+	// its conversation.
+	AddMessage(ctx context.Context, message *models.Message) error
+	// MarkRead marks the messages the reader received in the conversation
+	// as read and returns those that were unread, oldest first.
+	MarkRead(ctx context.Context, conversationID, readerID uint) ([]models.Message, error)
+}
+
+type SavedSearchRepository interface {
+	List(ctx context.Context, userID uint) ([]models.SavedSearch, error)
+	Get(ctx context.Context, id uint) (models.SavedSearch, error)
+	Create(ctx context.Context, search *models.SavedSearch) error
+	Update(ctx context.Context, search *models.SavedSearch) error
+	Delete(ctx context.Context, id uint) error
+	// Matching returns the saved searches the ad matches, leaving out
+	// those of the ad's owner.
+	Matching(ctx context.Context, adID uint) ([]models.SavedSearch, error)
+}
+
+type NotificationRepository interface {
+	// Create returns ErrDuplicate when the user was already notified of
+	// the ad for the same saved search.
+	Create(ctx context.Context, notification *models.Notification) error
+	// List returns the user's notifications, newest first.
+	List(ctx context.Context, userID uint, limit, offset int) ([]models.Notification, error)
+	// MarkRead returns the notification and reports whether it was unread.
+	// It returns ErrNotFound unless the notification belongs to the user.
+	MarkRead(ctx context.Context, userID, id uint) (models.Notification, bool, error)
+}
+
+// AdFilter selects and pages ads. Zero values mean "not set".
+// CategoryID matches the ads anywhere below the category. Cursor paging is by ascending ID and only applies when neither Query
+// nor Sort is set.
+type AdFilter struct {
+	Limit         int
+	Cursor        uint
+	Offset        int
+	CategoryID    uint
+	SubcategoryID uint
+	PriceMin      *int64
+	PriceMax      *int64
+	Currency      string
+	From          *time.Time
+	To            *time.Time
+	UserID        uint
+	Query         string
+	Sort          string
+	Status        string
+	Attributes    []AttributeFilter
+}
+
+// AttributeFilter matches ads by one of their attributes. Value compares
+// the text form of the attribute; Min and Max only match numbers.
+type AttributeFilter struct {
+	Name  string
+	Value string
+	Min   *float64
+	Max   *float64
+}
+
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+)
+
+// GeoFilter restricts ads to a circle around a point or to a bounding box
+// given as minLon, minLat, maxLon, maxLat. Distances are measured from the
+// point, which is the box center when only a box is given.
+type GeoFilter struct {
+	Lat    float64
+	Lon    float64
+	Radius float64
+	BBox   *[4]float64
+}
+
+type AdPage struct {
+	Ads     []models.AdDetails
+	Total   int64
+	HasMore bool
+}
+
+// AuditFilter selects and pages audit entries. Zero values mean "not
+// set". From and To bound the time of the entries, inclusive.
+type AuditFilter struct {
+	Limit     int
+	Offset    int
+	ActorID   uint
+	Entity    string
+	EntityID  uint
+	Action    string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+}
+
+type AuditPage struct {
+	Entries []models.AuditEntry
+	Total   int64
+	HasMore bool
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory keeps files in process. It serves them like Local does.
+type Memory struct {
+	mu      sync.RWMutex
+	baseURL string
+	files   map[string][]byte
+}
+
+func NewMemory(baseURL string) *Memory {
+	return &Memory{baseURL: baseURL, files: make(map[string][]byte)}
+}
+
+func (m *Memory) Put(ctx context.Context, key string, r io.Reader) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files[key] = data
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.files[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.files, key)
+	return nil
+}
+
+func (m *Memory) URL(key string) string {
+	return m.baseURL + key
+}
+
+func (m *Memory) Key(url string) (string, bool) {
+	key, ok := strings.CutPrefix(url, m.baseURL)
+	if !ok {
+		return "", false
+	}
+	if _, err := cleanKey(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+func (m *Memory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	data, ok := m.files[r.URL.Path]
+	m.mu.RUnlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, r.URL.Path, time.Time{}, bytes.NewReader(data))
+}
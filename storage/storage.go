@@ -0,0 +1,42 @@
+// Package storage keeps uploaded files such as ad pictures. Local stores
+// them on disk and Memory keeps them in process, for tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("file not found")
+	ErrInvalidKey = errors.New("invalid file key")
+)
+
+// Storage stores files under slash-separated keys such as
+// "ads/12/3f9c.jpg" and publishes them at stable URLs.
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing file.
+	Put(ctx context.Context, key string, r io.Reader) error
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the file stored under key.
+	URL(key string) string
+	// Key is the inverse of URL. It reports false for URLs that do not
+	// belong to this storage.
+	Key(url string) (string, bool)
+}
+
+// cleanKey rejects keys that are empty, absolute or climb out of the
+// storage root.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == "." || strings.HasPrefix(cleaned, "../") || cleaned == ".." {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}